@@ -5,6 +5,11 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -22,6 +27,7 @@ type queueDetails struct {
 	Types         helpers.SupportedTypes
 	RepoType      string
 	ScanType      string
+	Project       string
 	FileListData  helpers.Files
 	NotIndexCount int
 	TotalCount    int
@@ -33,11 +39,32 @@ type IndexedRepo struct {
 	Type    string `json:"type"`
 }
 
+//repoResult totals of a single repository check, or the roll up of several
+type repoResult struct {
+	Repo              string
+	TotalCount        int
+	NotIndexCount     int
+	NotIndexableCount int
+	NoExtCount        int
+	UnindexableMap    map[string]int
+	EmptyCache        bool     //remote repository with nothing cached, not the same as 0/0 indexed
+	EmptyCaches       []string //remote repositories with nothing cached in a roll up
+}
+
 type buildList struct {
 	Data []buildListData `json:"buildsNumbers"`
 }
 type buildListData struct {
-	Uri string `json:"uri"`
+	Uri     string `json:"uri"`
+	Started string `json:"started"`
+}
+
+type allBuilds struct {
+	Builds []allBuildsData `json:"builds"`
+}
+type allBuildsData struct {
+	Uri         string `json:"uri"`
+	LastStarted string `json:"lastStarted"`
 }
 
 func GetCheckCommand() components.Command {
@@ -68,6 +95,10 @@ func getCheckArguments() []components.Argument {
 			Name:        "build-single",
 			Description: "verify a speciic build",
 		},
+		{
+			Name:        "build-all",
+			Description: "verify all builds.",
+		},
 		{
 			Name:        "repo-list",
 			Description: "verify comma delimited list of repositories.",
@@ -83,18 +114,36 @@ func getCheckArguments() []components.Argument {
 	}
 }
 
+//getCheckFlags the check run flags, then the ones of the check command itself
 func getCheckFlags() []components.Flag {
+	flags := append(getCheckRunFlags(),
+		components.BoolFlag{
+			Name:         "showall",
+			Description:  "Show all results, scanned or not",
+			DefaultValue: false,
+		},
+		components.BoolFlag{
+			Name:         "watch",
+			Description:  "re-run the checks on an interval and only report changes",
+			DefaultValue: false,
+		},
+		components.StringFlag{
+			Name:         "interval",
+			Description:  "watch interval e.g. 30s, 5m",
+			DefaultValue: "5m",
+		},
+	)
+	return append(flags, getNotifyFlags()...)
+}
+
+//getCheckRunFlags flags read by the check engine, shared by every command that runs checks
+func getCheckRunFlags() []components.Flag {
 	return []components.Flag{
 		components.StringFlag{
 			Name:         "worker",
 			Description:  "Worker count for getting scan details",
 			DefaultValue: "5",
 		},
-		components.BoolFlag{
-			Name:         "showall",
-			Description:  "Show all results, scanned or not",
-			DefaultValue: false,
-		},
 		components.BoolFlag{
 			Name:         "experimental",
 			Description:  "experimental scan details (artifacts only) - disabled",
@@ -105,6 +154,61 @@ func getCheckFlags() []components.Flag {
 			Description:  "force reindex unscanned artifacts",
 			DefaultValue: false,
 		},
+		components.BoolFlag{
+			Name:         "deep",
+			Description:  "verify every module artifact and dependency of a build, not just the build",
+			DefaultValue: false,
+		},
+		components.StringFlag{
+			Name:         "latest",
+			Description:  "only verify the latest N numbers of each build, 0 for all",
+			DefaultValue: "0",
+		},
+		components.StringFlag{
+			Name:         "project",
+			Description:  "JFrog project key to scope builds and repositories to",
+			DefaultValue: "",
+		},
+		components.StringFlag{
+			Name:         "pkg-type",
+			Description:  "comma delimited list of package types to check with repo-all e.g. docker,npm",
+			DefaultValue: "",
+		},
+		components.StringFlag{
+			Name:         "repo-type",
+			Description:  "comma delimited list of repository types to check with repo-all e.g. local,remote",
+			DefaultValue: "",
+		},
+	}
+}
+
+func getNotifyFlags() []components.Flag {
+	return []components.Flag{
+		components.StringFlag{
+			Name:         "notify-url",
+			Description:  "webhook to POST a JSON summary to at the end of the check",
+			DefaultValue: "",
+		},
+		components.StringFlag{
+			Name:         "notify-slack",
+			Description:  "Slack compatible incoming webhook to also send a message to",
+			DefaultValue: "",
+		},
+		components.StringFlag{
+			Name:         "notify-timeout",
+			Description:  "timeout of each notification attempt",
+			DefaultValue: "10s",
+		},
+		components.StringFlag{
+			Name:         "notify-retry",
+			Description:  "number of times to retry a failed notification",
+			DefaultValue: "3",
+		},
+		components.StringFlag{
+			Name:         "notify-threshold",
+			Description:  "in watch mode, notify when the unscanned count reaches this value, 0 to disable",
+			DefaultValue: "0",
+		},
 	}
 }
 
@@ -120,24 +224,135 @@ type CheckConfiguration struct {
 	min       bool
 }
 
+//checkOptions flags of a check run, read once so checks can also run without a cli context
+type checkOptions struct {
+	workers      int
+	showAll      bool
+	experimental bool
+	reindex      bool
+	deep         bool
+	latest       int
+	project      string
+	pkgTypes     []string
+	repoTypes    []string
+	out          io.Writer
+	//notifyThreshold unscanned count that triggers a notification in watch mode, 0 to disable
+	notifyThreshold int
+	notify          *notifier
+	report          *checkReport
+}
+
+func getCheckOptions(c *components.Context) (*checkOptions, error) {
+	opts := &checkOptions{
+		showAll:      c.GetBoolFlagValue("showall"),
+		experimental: c.GetBoolFlagValue("experimental"),
+		reindex:      c.GetBoolFlagValue("reindex"),
+		deep:         c.GetBoolFlagValue("deep"),
+		project:      c.GetStringFlagValue("project"),
+		pkgTypes:     splitFlagList(c.GetStringFlagValue("pkg-type")),
+		repoTypes:    splitFlagList(c.GetStringFlagValue("repo-type")),
+		out:          os.Stdout,
+		report:       newCheckReport(),
+	}
+	workers, err := strconv.Atoi(c.GetStringFlagValue("worker"))
+	if err != nil {
+		fmt.Println("error setting workers, using default of 5:", err)
+		workers = 5
+	}
+	opts.workers = workers
+	latest, err := strconv.Atoi(c.GetStringFlagValue("latest"))
+	if err != nil {
+		return nil, errors.New("Invalid latest value:" + c.GetStringFlagValue("latest"))
+	}
+	opts.latest = latest
+	opts.notify, err = newNotifier(c.GetStringFlagValue("notify-url"), c.GetStringFlagValue("notify-slack"), c.GetStringFlagValue("notify-timeout"), c.GetStringFlagValue("notify-retry"))
+	if err != nil {
+		return nil, err
+	}
+	//commands reusing the check engine may not have the notify flags
+	if value := c.GetStringFlagValue("notify-threshold"); value != "" {
+		opts.notifyThreshold, err = strconv.Atoi(value)
+		if err != nil {
+			return nil, errors.New("Invalid notify threshold value:" + value)
+		}
+	}
+	return opts, nil
+}
+
+//rerun copy of the options for another run of the same checks, with a fresh report
+func (opts *checkOptions) rerun(out io.Writer) *checkOptions {
+	next := *opts
+	next.out = out
+	next.report = newCheckReport()
+	return &next
+}
+
 func CheckCmd(c *components.Context) error {
-	timeStart := time.Now()
-	config, err := helpers.GetConfig()
+	config, err := helpers.GetConfig(helpers.MetricsXray)
 	if err != nil {
 		return errors.New(err.Error() + " at " + string(helpers.Trace().Fn) + " on line " + string(strconv.Itoa(helpers.Trace().Line)))
 	}
 	if len(c.Arguments) == 0 {
 		return errors.New("Please provide appropiate arguments")
 	}
+	opts, err := getCheckOptions(c)
+	if err != nil {
+		return err
+	}
+
+	if c.GetBoolFlagValue("watch") {
+		interval, err := time.ParseDuration(c.GetStringFlagValue("interval"))
+		if err != nil || interval <= 0 {
+			return errors.New("Invalid interval value:" + c.GetStringFlagValue("interval"))
+		}
+		return watchChecks(c.Arguments, interval, config, opts)
+	}
+
+	return runAndNotify(c.Arguments, config, opts)
+}
+
+//runAndNotify notify whatever the result, a check that fails is what most needs to be heard about
+func runAndNotify(args []string, config *config.ServerDetails, opts *checkOptions) error {
+	timeStart := time.Now()
+	err := runChecks(args, config, opts)
+	totalTime := time.Since(timeStart)
+	fmt.Fprintln(opts.out, "Execution took:", totalTime)
+	if opts.notify != nil {
+		summary := newNotifySummary("check", args, config.Url, totalTime, opts.report)
+		if err != nil {
+			summary.Error = err.Error()
+		}
+		notifyErr := opts.notify.send(summary)
+		if notifyErr != nil {
+			log.Error("Failed to send notification:", notifyErr)
+		}
+	}
+	return err
+}
 
+//runChecks run the check selected by the arguments, results are added to the options report
+func runChecks(args []string, config *config.ServerDetails, opts *checkOptions) error {
+	var err error
 	indexedMap := make(map[string]IndexedRepo)
 	var supportedTypes helpers.SupportedTypes
-	if strings.HasPrefix(c.Arguments[0], "repo-") {
+	if opts.deep && !strings.HasPrefix(args[0], "build-") {
+		return errors.New("--deep only applies to build-single, build-list and build-all")
+	}
+	if strings.HasPrefix(args[0], "repo-") {
 		supportedTypes, err = helpers.GetSupportedTypesJSON()
 		if err != nil {
 			return err
 		}
+	}
+	//deep build checks need the indexed repositories to resolve build artifacts
+	if strings.HasPrefix(args[0], "repo-") || opts.deep {
 		indexList := CheckTypeAndRepoParams(config)
+		if opts.project != "" {
+			indexList, err = filterProjectRepos(indexList, opts.project, config)
+			if err != nil {
+				return err
+			}
+		}
 		//convert to map for speedier look up
 		for i := 0; i < len(indexList); i += 1 {
 			indexedMap[indexList[i].Name] = indexList[i]
@@ -145,48 +360,81 @@ func CheckCmd(c *components.Context) error {
 	}
 
 	// probably not the right way to do it
-	if len(c.Arguments) > 0 && len(c.Arguments) < 4 {
-		var err error
-		switch arg := c.Arguments[0]; arg {
+	if len(args) > 0 && len(args) < 4 {
+		switch arg := args[0]; arg {
 		case "repo-all":
-			fmt.Println("This may take a while")
-			for i := range indexedMap {
-				log.Debug("sending " + indexedMap[i].Name + " for validation")
-				err = validateCheck(indexedMap[i].Name, "", indexedMap, supportedTypes, config, c)
+			fmt.Fprintln(opts.out, "This may take a while")
+			repos := filterIndexedRepos(indexedMap, opts.pkgTypes, opts.repoTypes)
+			if len(repos) == 0 {
+				return errors.New("no repositories marked for indexing match the given filters")
+			}
+			//one repository failing should not keep the others from being checked
+			var failed []string
+			for i := range repos {
+				log.Debug("sending " + repos[i].Name + " for validation")
+				err = validateCheck(repos[i].Name, "", indexedMap, supportedTypes, config, opts)
 				if err != nil {
-					break
+					log.Warn(err)
+					failed = append(failed, err.Error())
 				}
 			}
+			if len(failed) > 0 {
+				return errors.New(strconv.Itoa(len(failed)) + " of " + strconv.Itoa(len(repos)) + " repositories failed: " + strings.Join(failed, "; "))
+			}
 			return nil
 		case "repo-list":
-			repos := strings.Split(c.Arguments[1], ",")
+			if len(args) == 1 {
+				return errors.New("missing repository list")
+			}
+			repos := strings.Split(args[1], ",")
 			for repo := range repos {
-				err = validateCheck(repos[repo], "", indexedMap, supportedTypes, config, c)
+				err = validateCheck(repos[repo], "", indexedMap, supportedTypes, config, opts)
 				if err != nil {
 					break
 				}
 			}
 		case "repo-single":
+			if len(args) == 1 {
+				return errors.New("missing repository name")
+			}
 			//check repo, and get type
-			err = validateCheck(c.Arguments[1], "", indexedMap, supportedTypes, config, c)
+			err = validateCheck(args[1], "", indexedMap, supportedTypes, config, opts)
 		case "repo-path":
-			if len(c.Arguments) == 2 {
+			if len(args) < 3 {
 				return errors.New("missing path")
 			}
-			path := c.Arguments[2]
+			path := args[2]
 			if !strings.HasPrefix(path, "/") {
 				path = "/" + path //api requires leading forward slash
 			}
-			err = validateCheck(c.Arguments[1], path, indexedMap, supportedTypes, config, c)
+			err = validateCheck(args[1], path, indexedMap, supportedTypes, config, opts)
 		case "build-single":
-			if len(c.Arguments) == 1 {
+			if len(args) == 1 {
 				return errors.New("missing build name")
 			}
-			err = indexBuild(c.Arguments[1], config, c)
+			err = indexBuild(args[1], indexedMap, config, opts)
+		case "build-all":
+			var buildNames []string
+			buildNames, err = getBuildNames(opts.project, config)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(opts.out, "Found", len(buildNames), "builds, this may take a while")
+			for i := range buildNames {
+				err = indexBuild(buildNames[i], indexedMap, config, opts)
+				if err != nil {
+					//builds without any numbers left should not stop the rest
+					log.Warn(err)
+					err = nil
+				}
+			}
 		case "build-list":
-			builds := strings.Split(c.Arguments[1], ",")
+			if len(args) == 1 {
+				return errors.New("missing build list")
+			}
+			builds := strings.Split(args[1], ",")
 			for build := range builds {
-				err = indexBuild(builds[build], config, c)
+				err = indexBuild(builds[build], indexedMap, config, opts)
 				if err != nil {
 					break
 				}
@@ -194,34 +442,149 @@ func CheckCmd(c *components.Context) error {
 		default:
 			return errors.New("non existent argument:" + arg)
 		}
-		if err != nil {
-			return err
-		}
-		endTime := time.Now()
-		totalTime := endTime.Sub(timeStart)
-		fmt.Println("Execution took:", totalTime)
-		return nil
+		return err
 	}
 	//return if wrong num arg
-	return errors.New("Wrong number of arguments. Expected: 1-3, " + "Received: " + strconv.Itoa(len(c.Arguments)))
+	return errors.New("Wrong number of arguments. Expected: 1-3, " + "Received: " + strconv.Itoa(len(args)))
 
 }
 
-func validateCheck(repoName, path string, indexedMap map[string]IndexedRepo, supportedTypes helpers.SupportedTypes, config *config.ServerDetails, c *components.Context) error {
+func validateCheck(repoName, path string, indexedMap map[string]IndexedRepo, supportedTypes helpers.SupportedTypes, config *config.ServerDetails, opts *checkOptions) error {
 	//check repo, and get type
-	repo := indexedMap[repoName]
-	if repo.Name == "" {
-		return errors.New("repository " + repoName + " does not exist or is not marked for indexing")
+	repo, ok := lookupIndexedRepo(repoName, indexedMap)
+	if !ok {
+		//virtual repositories are never indexed themselves, but their members can be
+		repoConfig, err := helpers.GetRepoConfig(repoName, config)
+		if err != nil || repoConfig.Rclass != "virtual" {
+			return errors.New("repository " + repoName + " does not exist or is not marked for indexing")
+		}
+		return validateVirtual(repoName, path, indexedMap, supportedTypes, config, opts)
+	}
+	fmt.Fprintln(opts.out, "checking:"+repoName+" at path:"+path)
+	indexRepo(repo, supportedTypes, config, path, opts)
+	return nil
+}
+
+//filterIndexedRepos repositories matching any of the package types and any of the repository types, empty filters match everything
+func filterIndexedRepos(indexedMap map[string]IndexedRepo, pkgTypes, repoTypes []string) []IndexedRepo {
+	var repos []IndexedRepo
+	for _, repo := range indexedMap {
+		if len(pkgTypes) > 0 && !containsFold(pkgTypes, repo.PkgType) {
+			continue
+		}
+		if len(repoTypes) > 0 && !containsFold(repoTypes, repo.Type) {
+			continue
+		}
+		repos = append(repos, repo)
+	}
+	sort.Slice(repos, func(i, j int) bool { return repos[i].Name < repos[j].Name })
+	return repos
+}
+
+//splitFlagList split a comma delimited flag value, ignoring empty entries
+func splitFlagList(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		v = strings.TrimSpace(v)
+		if v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+func containsFold(list []string, value string) bool {
+	for i := range list {
+		if strings.EqualFold(list[i], value) {
+			return true
+		}
+	}
+	return false
+}
+
+func validateVirtual(repoName, path string, indexedMap map[string]IndexedRepo, supportedTypes helpers.SupportedTypes, config *config.ServerDetails, opts *checkOptions) error {
+	members, skipped, err := expandVirtual(repoName, indexedMap, func(name string) (helpers.RepoConfig, error) {
+		return helpers.GetRepoConfig(name, config)
+	})
+	if err != nil {
+		return err
+	}
+	for i := range skipped {
+		fmt.Fprintln(opts.out, "skipping:"+skipped[i]+" member of "+repoName+" is not marked for indexing")
+	}
+	if len(members) == 0 {
+		return errors.New("virtual repository " + repoName + " has no members marked for indexing")
+	}
+
+	rollup := repoResult{Repo: repoName, UnindexableMap: make(map[string]int)}
+	for i := range members {
+		fmt.Fprintln(opts.out, "checking:"+members[i].Name+" (member of "+repoName+") at path:"+path)
+		result := indexRepo(members[i], supportedTypes, config, path, opts)
+		rollup.add(result)
+	}
+	fmt.Fprintln(opts.out, "Total "+repoName+" (virtual) indexed count:", rollup.TotalCount-rollup.NotIndexCount, "/", rollup.TotalCount, " Total not indexable:", rollup.NotIndexableCount, " Files with no extension:", rollup.NoExtCount)
+	fmt.Fprintln(opts.out, "Unindexable file types count:", rollup.UnindexableMap)
+	if len(rollup.EmptyCaches) > 0 {
+		fmt.Fprintln(opts.out, "Remote repositories with an empty cache:", strings.Join(rollup.EmptyCaches, ","))
 	}
-	fmt.Println("checking:" + repoName + " at path:" + path)
-	indexRepo(repo.Name, repo.PkgType, supportedTypes, repo.Type, config, path, c)
 	return nil
 }
 
-func indexBuild(buildName string, config *config.ServerDetails, c *components.Context) error {
+//expandVirtual resolve a virtual repository to its indexed local and remote members, nested virtuals are followed
+func expandVirtual(repoName string, indexedMap map[string]IndexedRepo, getRepoConfig func(string) (helpers.RepoConfig, error)) ([]IndexedRepo, []string, error) {
+	var members []IndexedRepo
+	var skipped []string
+	visited := map[string]bool{repoName: true}
+	pending := []string{repoName}
+	for len(pending) > 0 {
+		current := pending[0]
+		pending = pending[1:]
+		repoConfig, err := getRepoConfig(current)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, member := range repoConfig.Repositories {
+			if visited[member] {
+				continue
+			}
+			visited[member] = true
+			if indexed, ok := indexedMap[member]; ok {
+				members = append(members, indexed)
+				continue
+			}
+			memberConfig, err := getRepoConfig(member)
+			if err == nil && memberConfig.Rclass == "virtual" {
+				pending = append(pending, member)
+				continue
+			}
+			skipped = append(skipped, member)
+		}
+	}
+	return members, skipped, nil
+}
+
+func (r *repoResult) add(result repoResult) {
+	r.TotalCount += result.TotalCount
+	r.NotIndexCount += result.NotIndexCount
+	r.NotIndexableCount += result.NotIndexableCount
+	r.NoExtCount += result.NoExtCount
+	if result.EmptyCache {
+		r.EmptyCaches = append(r.EmptyCaches, result.Repo)
+	}
+	r.EmptyCaches = append(r.EmptyCaches, result.EmptyCaches...)
+	if r.UnindexableMap == nil {
+		r.UnindexableMap = make(map[string]int)
+	}
+	for ext, count := range result.UnindexableMap {
+		r.UnindexableMap[ext] += count
+	}
+}
+
+func indexBuild(buildName string, indexedMap map[string]IndexedRepo, config *config.ServerDetails, opts *checkOptions) error {
 	var buildListStruct buildList
 	var notIndexCount, totalCount int
-	buildListData, respCode, _ := helpers.GetRestAPI("GET", true, config.ArtifactoryUrl+"api/build/"+buildName, config, "", nil, 0)
+	project := opts.project
+	buildListData, respCode, _ := helpers.GetRestAPI("GET", true, config.ArtifactoryUrl+"api/build/"+url.PathEscape(buildName)+projectQuery(project), config, "", nil, 0)
 	if respCode != 200 {
 		return errors.New("Build list received unexpected response code:" + strconv.Itoa(respCode) + " :" + string(buildListData))
 	}
@@ -229,10 +592,12 @@ func indexBuild(buildName string, config *config.ServerDetails, c *components.Co
 	if len(buildListStruct.Data) == 0 {
 		return errors.New("No build versions found for:" + buildName)
 	}
+	buildListStruct.Data = selectLatestBuilds(buildListStruct.Data, opts.latest)
 	buildAnalysis := list.New()
 	for i := range buildListStruct.Data {
 		var queueDetails queueDetails
 		queueDetails.Repo = buildName
+		queueDetails.Project = project
 		var fileData helpers.Files
 		fileData.Uri = strings.TrimPrefix(buildListStruct.Data[i].Uri, "/")
 		queueDetails.FileListData = fileData
@@ -242,15 +607,135 @@ func indexBuild(buildName string, config *config.ServerDetails, c *components.Co
 		buildAnalysis.PushBack(queueDetails)
 	}
 
-	totalCount, notIndexCount = workerPool(buildAnalysis, config, c, totalCount, notIndexCount)
-	fmt.Println("Total "+buildName+" scanned count:", totalCount-notIndexCount, "/", totalCount)
+	totalCount, notIndexCount = workerPool(buildAnalysis, config, opts, totalCount, notIndexCount)
+	fmt.Fprintln(opts.out, "Total "+buildName+" scanned count:", totalCount-notIndexCount, "/", totalCount)
+	opts.report.addRepo(repoResult{Repo: buildName, TotalCount: totalCount, NotIndexCount: notIndexCount})
 
+	if opts.deep {
+		for i := range buildListStruct.Data {
+			err := deepCheckBuild(buildName, strings.TrimPrefix(buildListStruct.Data[i].Uri, "/"), project, indexedMap, config, opts)
+			if err != nil {
+				log.Warn(err)
+			}
+		}
+	}
 	return nil
 }
 
-func indexRepo(repo string, pkgType string, types helpers.SupportedTypes, repoType string, config *config.ServerDetails, folder string, c *components.Context) {
+//getBuildNames list the name of every build, decoded from the build uri
+func getBuildNames(project string, config *config.ServerDetails) ([]string, error) {
+	var allBuildsStruct allBuilds
+	allBuildsData, respCode, _ := helpers.GetRestAPI("GET", true, config.ArtifactoryUrl+"api/build"+projectQuery(project), config, "", nil, 0)
+	if respCode != 200 {
+		return nil, errors.New("All builds list received unexpected response code:" + strconv.Itoa(respCode) + " :" + string(allBuildsData))
+	}
+	err := json.Unmarshal(allBuildsData, &allBuildsStruct)
+	if err != nil {
+		return nil, errors.New(err.Error() + " at " + string(helpers.Trace().Fn) + " on line " + string(strconv.Itoa(helpers.Trace().Line)))
+	}
+	var buildNames []string
+	for i := range allBuildsStruct.Builds {
+		buildName, err := url.PathUnescape(strings.TrimPrefix(allBuildsStruct.Builds[i].Uri, "/"))
+		if err != nil {
+			log.Warn("Skipping build with invalid uri:", allBuildsStruct.Builds[i].Uri)
+			continue
+		}
+		buildNames = append(buildNames, buildName)
+	}
+	sort.Sort(Alphabetic(buildNames))
+	return buildNames, nil
+}
+
+//selectLatestBuilds keep the latest build numbers by start time, 0 or less keeps them all
+func selectLatestBuilds(builds []buildListData, latest int) []buildListData {
+	if latest <= 0 || latest >= len(builds) {
+		return builds
+	}
+	sorted := make([]buildListData, len(builds))
+	copy(sorted, builds)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return buildStartTime(sorted[i]).After(buildStartTime(sorted[j]))
+	})
+	return sorted[:latest]
+}
+
+func buildStartTime(build buildListData) time.Time {
+	started, err := time.Parse("2006-01-02T15:04:05.000-0700", build.Started)
+	if err != nil {
+		log.Debug("Failed to parse build start time:", build.Started)
+		return time.Time{}
+	}
+	return started
+}
+
+//reindexRequest body of the Xray force reindex API
+type reindexRequest struct {
+	Artifacts []reindexArtifact `json:"artifacts,omitempty"`
+	Builds    []reindexBuild    `json:"builds,omitempty"`
+}
+
+type reindexArtifact struct {
+	Repository string `json:"repository"`
+	Path       string `json:"path"`
+}
+
+type reindexBuild struct {
+	Name    string `json:"name"`
+	Number  string `json:"number"`
+	Project string `json:"project,omitempty"`
+}
+
+//reindexBody force reindex request of an artifact or build, empty for other scan types
+func reindexBody(q queueDetails) string {
+	var request reindexRequest
+	switch q.ScanType {
+	case "artifact":
+		request.Artifacts = []reindexArtifact{{Repository: q.Repo, Path: q.FileListData.Uri}}
+	case "build":
+		//re-use repo = build name, uri = build number
+		request.Builds = []reindexBuild{{Name: q.Repo, Number: q.FileListData.Uri, Project: q.Project}}
+	default:
+		return ""
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		log.Warn("Failed to build reindex request:", err)
+		return ""
+	}
+	return string(body)
+}
+
+//projectQuery query string scoping build APIs to a project, builds outside of projects live in the default build-info repository
+func projectQuery(project string) string {
+	if project == "" {
+		return ""
+	}
+	return "?project=" + url.QueryEscape(project)
+}
+
+//filterProjectRepos keep only the indexed repositories that belong to the project
+func filterProjectRepos(indexList []IndexedRepo, project string, config *config.ServerDetails) ([]IndexedRepo, error) {
+	projectRepos, err := helpers.GetProjectRepos(project, config)
+	if err != nil {
+		return nil, err
+	}
+	inProject := make(map[string]bool)
+	for i := range projectRepos {
+		inProject[projectRepos[i]] = true
+	}
+	var result []IndexedRepo
+	for i := range indexList {
+		if inProject[indexList[i].Name] {
+			result = append(result, indexList[i])
+		}
+	}
+	return result, nil
+}
+
+func indexRepo(indexedRepo IndexedRepo, types helpers.SupportedTypes, config *config.ServerDetails, folder string, opts *checkOptions) repoResult {
 	var extensions []helpers.Extensions
-	pkgType = strings.ToLower(pkgType)
+	resolved := resolveRepoType(indexedRepo)
+	repo, pkgType, repoType := resolved.StorageName, resolved.PkgType, resolved.Type
 	log.Debug("type:", repoType, " pkgType:", pkgType, " repo:", repo)
 	for i := range types.SupportedPackageTypes {
 		if types.SupportedPackageTypes[i].Type == pkgType {
@@ -266,22 +751,28 @@ func indexRepo(repo string, pkgType string, types helpers.SupportedTypes, repoTy
 	}
 	var fileListData []byte
 	var respCode int
-	if repoType == "remote" {
-		if !strings.HasSuffix(repo, "-cache") {
-			repo = repo + "-cache"
-		}
-	}
 	//use content reader for larger amounts of data, or only allow path
 	fileListData, respCode, _ = helpers.GetRestAPI("GET", true, config.ArtifactoryUrl+"api/storage/"+repo+folder+"?list&deep=1", config, "", nil, 0)
+	if respCode == 404 && resolved.isRemote() {
+		//cache is only created once something has been downloaded through the remote
+		fmt.Fprintln(opts.out, "Total "+repo+" remote cache is empty, nothing to index")
+		opts.report.addRepo(repoResult{Repo: repo, EmptyCache: true})
+		return repoResult{Repo: repo, EmptyCache: true}
+	}
 	if respCode != 200 {
 		log.Error("File list received unexpected response code:", respCode, " :", string(fileListData))
-		return
+		return repoResult{Repo: repo}
 	}
 	log.Debug("File list received:", string(fileListData))
 
 	var UnindexableMap = make(map[string]int)
 	var fileListStruct helpers.FileList
 	json.Unmarshal(fileListData, &fileListStruct)
+	if len(fileListStruct.Files) == 0 && resolved.isRemote() {
+		fmt.Fprintln(opts.out, "Total "+repo+" remote cache is empty, nothing to index")
+		opts.report.addRepo(repoResult{Repo: repo, EmptyCache: true})
+		return repoResult{Repo: repo, EmptyCache: true}
+	}
 	var notIndexCount, totalCount, notIndexableCount, noExtCount int
 	indexAnalysis := list.New()
 	for i := range fileListStruct.Files {
@@ -317,27 +808,33 @@ func indexRepo(repo string, pkgType string, types helpers.SupportedTypes, repoTy
 			}
 		}
 	}
-	totalCount, notIndexCount = workerPool(indexAnalysis, config, c, totalCount, notIndexCount)
-	fmt.Println("Total "+repo+" indexed count:", totalCount-notIndexCount, "/", totalCount, " Total not indexable:", notIndexableCount, " Files with no extension:", noExtCount)
-	fmt.Println("Unindexable file types count:", UnindexableMap)
+	totalCount, notIndexCount = workerPool(indexAnalysis, config, opts, totalCount, notIndexCount)
+	fmt.Fprintln(opts.out, "Total "+repo+" indexed count:", totalCount-notIndexCount, "/", totalCount, " Total not indexable:", notIndexableCount, " Files with no extension:", noExtCount)
+	fmt.Fprintln(opts.out, "Unindexable file types count:", UnindexableMap)
+	result := repoResult{
+		Repo:              repo,
+		TotalCount:        totalCount,
+		NotIndexCount:     notIndexCount,
+		NotIndexableCount: notIndexableCount,
+		NoExtCount:        noExtCount,
+		UnindexableMap:    UnindexableMap,
+	}
+	opts.report.addRepo(result)
+	return result
 }
 
-func workerPool(indexAnalysis *list.List, config *config.ServerDetails, c *components.Context, totalCount, notIndexCount int) (int, int) {
+func workerPool(indexAnalysis *list.List, config *config.ServerDetails, opts *checkOptions, totalCount, notIndexCount int) (int, int) {
 	numJobs := indexAnalysis.Len()
-	jobs := make(chan int, numJobs)
+	jobs := make(chan queueDetails, numJobs)
 	results := make(chan int, numJobs)
+	opts.report.addQueued(numJobs)
 
-	workers, err := strconv.Atoi(c.GetStringFlagValue("worker"))
-	if err != nil {
-		fmt.Println("error setting workers, using default of 5:", err)
-		workers = 5
-	}
-
-	for w := 1; w <= workers; w++ {
-		go worker(w, jobs, results, indexAnalysis, config, c)
+	for w := 1; w <= opts.workers; w++ {
+		go worker(w, jobs, results, config, opts)
 	}
-	for j := 1; j <= numJobs; j++ {
-		jobs <- j
+	//workers only take from the channel, the list is not safe to share between them
+	for e := indexAnalysis.Front(); e != nil; e = e.Next() {
+		jobs <- e.Value.(queueDetails)
 	}
 	close(jobs)
 	var x int
@@ -347,16 +844,15 @@ func workerPool(indexAnalysis *list.List, config *config.ServerDetails, c *compo
 			notIndexCount++
 		}
 		totalCount++
+		opts.report.addDone()
 	}
 	return totalCount, notIndexCount
 }
 
-func worker(id int, jobs <-chan int, results chan<- int, queue *list.List, config *config.ServerDetails, c *components.Context) {
-	for queue.Len() > 0 {
-		e := queue.Front().Value
-		queue.Remove(queue.Front())
+func worker(id int, jobs <-chan queueDetails, results chan<- int, config *config.ServerDetails, opts *checkOptions) {
+	for e := range jobs {
 		log.Debug("worker ", id, " working on ", e)
-		notIndexCount, totalCount := Details(e.(queueDetails), config, c)
+		notIndexCount, totalCount := Details(e, config, opts)
 		log.Debug("not index:", notIndexCount, " total:", totalCount)
 		results <- totalCount
 	}
@@ -375,35 +871,27 @@ func CheckTypeAndRepoParams(config *config.ServerDetails) []IndexedRepo {
 	return result
 }
 
-func Details(q queueDetails, config *config.ServerDetails, c *components.Context) (int, int) {
+func Details(q queueDetails, config *config.ServerDetails, opts *checkOptions) (int, int) {
 	//send to details
 	var status string
 	var proc bool
-	if c.GetBoolFlagValue("experimental") && q.ScanType == "artifact" {
+	if opts.experimental && q.ScanType == "artifact" {
 		// status, proc = internal.GetDetails(q.Repo, q.PkgType, q.FileListData.Uri, config)
-		status, proc = helpers.GetStatus(q.Repo, q.PkgType, q.FileListData.Uri, q.FileListData.Sha256, q.ScanType, config)
+		status, proc = helpers.GetStatus(q.Repo, q.PkgType, q.FileListData.Uri, q.FileListData.Sha256, q.ScanType, q.Project, config)
 	} else {
-		status, proc = helpers.GetStatus(q.Repo, q.PkgType, q.FileListData.Uri, q.FileListData.Sha256, q.ScanType, config)
+		status, proc = helpers.GetStatus(q.Repo, q.PkgType, q.FileListData.Uri, q.FileListData.Sha256, q.ScanType, q.Project, config)
 	}
+	opts.report.addStatus(artifactStatus{Repo: q.Repo, Path: q.FileListData.Uri, ScanType: q.ScanType, Status: status, Scanned: proc})
 	if !proc {
 		q.NotIndexCount++
-		printStatus(status, q.Repo, q.PkgType, q.FileListData.Uri, config)
+		printStatus(status, q.Repo, q.PkgType, q.FileListData.Uri, config, opts)
 		//reindex if needed:
-		if c.GetBoolFlagValue("reindex") {
+		if opts.reindex {
 			m := map[string]string{
 				"Content-Type": "application/json",
 			}
-			var body string
-			switch q.ScanType {
-			case "artifact":
-				body = "{\"artifacts\": [{\"repository\":\"" + q.Repo + "\",\"path\":\"" + q.FileListData.Uri + "\"}]}"
-			case "build":
-				//re-use repo = build name, uri = build number
-				body = "{\"builds\": [{\"name\":\"" + q.Repo + "\",\"number\":\"" + q.FileListData.Uri + "\"}]}"
-			case "releaseBundle":
-			default:
-			}
-			fmt.Println(body)
+			body := reindexBody(q)
+			fmt.Fprintln(opts.out, body)
 			resp, respCode, _ := helpers.GetRestAPI("POST", true, config.XrayUrl+"api/v1/forceReindex", config, body, m, 0)
 			if respCode != 200 {
 				log.Warn("Unexpected Xray response:HTTP", respCode, " ", string(resp))
@@ -413,15 +901,19 @@ func Details(q queueDetails, config *config.ServerDetails, c *components.Context
 		}
 	} else {
 		q.TotalCount++
-		if c.GetBoolFlagValue("showall") {
-			printStatus(status, q.Repo, q.PkgType, q.FileListData.Uri, config)
+		if opts.showAll {
+			printStatus(status, q.Repo, q.PkgType, q.FileListData.Uri, config, opts)
 		}
 	}
 	//log.Info("not index:", q.NotIndexCount, " total:", q.TotalCount)
 	return q.NotIndexCount, q.TotalCount
 }
 
-func printStatus(status string, repo string, pkgType string, uri string, config *config.ServerDetails) error {
+func printStatus(status string, repo string, pkgType string, uri string, config *config.ServerDetails, opts *checkOptions) error {
+	//skip the size look ups when nothing is printed
+	if opts.out == ioutil.Discard {
+		return nil
+	}
 	var fileDetails []byte
 	var fileInfo helpers.FileInfo
 	var size string
@@ -462,6 +954,6 @@ func printStatus(status string, repo string, pkgType string, uri string, config
 	status = fmt.Sprintf("%-19v", status)
 	size = fmt.Sprintf("%-10v", size)
 	//not really helpful for docker
-	fmt.Println(status, "\t", size, "\t", fmt.Sprintf("%-25v", strings.TrimPrefix(fileInfo.MimeType, "application/")), " ", repo+":"+uri)
+	fmt.Fprintln(opts.out, status, "\t", size, "\t", fmt.Sprintf("%-25v", strings.TrimPrefix(fileInfo.MimeType, "application/")), " ", repo+":"+uri)
 	return nil
 }
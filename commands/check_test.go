@@ -1,7 +1,106 @@
 package commands
 
-import "testing"
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jfrog/jfrog-cli-core/v2/utils/config"
+	helpers "github.com/lorenyeung/indexcheck/utils"
+	"github.com/stretchr/testify/assert"
+)
 
 func TestCheckTypeAndRepoParams(t *testing.T) {
 
 }
+
+func TestExpandVirtual(t *testing.T) {
+	indexedMap := map[string]IndexedRepo{
+		"npm-local":  {Name: "npm-local", PkgType: "npm", Type: "local"},
+		"npm-remote": {Name: "npm-remote", PkgType: "npm", Type: "remote"},
+		"npm-team":   {Name: "npm-team", PkgType: "npm", Type: "local"},
+	}
+	configs := map[string]helpers.RepoConfig{
+		"npm":           {Key: "npm", Rclass: "virtual", Repositories: []string{"npm-local", "npm-nested", "npm-unindexed", "npm-remote"}},
+		"npm-nested":    {Key: "npm-nested", Rclass: "virtual", Repositories: []string{"npm-team", "npm-local", "npm"}},
+		"npm-unindexed": {Key: "npm-unindexed", Rclass: "local"},
+	}
+	getRepoConfig := func(name string) (helpers.RepoConfig, error) {
+		repoConfig, ok := configs[name]
+		if !ok {
+			return repoConfig, errors.New("not found")
+		}
+		return repoConfig, nil
+	}
+
+	members, skipped, err := expandVirtual("npm", indexedMap, getRepoConfig)
+	assert.NoError(t, err)
+	assert.Equal(t, []IndexedRepo{indexedMap["npm-local"], indexedMap["npm-remote"], indexedMap["npm-team"]}, members)
+	assert.Equal(t, []string{"npm-unindexed"}, skipped)
+
+	_, _, err = expandVirtual("missing", indexedMap, getRepoConfig)
+	assert.Error(t, err)
+}
+
+func TestFilterIndexedRepos(t *testing.T) {
+	indexedMap := map[string]IndexedRepo{
+		"docker-remote": {Name: "docker-remote", PkgType: "Docker", Type: "remote"},
+		"docker-local":  {Name: "docker-local", PkgType: "Docker", Type: "local"},
+		"npm-local":     {Name: "npm-local", PkgType: "Npm", Type: "local"},
+		"go-fed":        {Name: "go-fed", PkgType: "Go", Type: "federated"},
+	}
+	assert.Len(t, filterIndexedRepos(indexedMap, nil, nil), 4)
+	assert.Equal(t, []IndexedRepo{indexedMap["docker-local"], indexedMap["docker-remote"]}, filterIndexedRepos(indexedMap, splitFlagList("docker"), nil))
+	assert.Equal(t, []IndexedRepo{indexedMap["docker-local"], indexedMap["npm-local"]}, filterIndexedRepos(indexedMap, splitFlagList("docker, npm"), splitFlagList("local")))
+	assert.Empty(t, filterIndexedRepos(indexedMap, splitFlagList("go"), splitFlagList("local,remote")))
+}
+
+func TestSelectLatestBuilds(t *testing.T) {
+	builds := []buildListData{
+		{Uri: "/1", Started: "2021-11-20T10:00:00.000+0000"},
+		{Uri: "/3", Started: "2021-11-22T10:00:00.000+0000"},
+		{Uri: "/2", Started: "2021-11-21T12:00:00.000+0200"},
+	}
+	assert.Equal(t, builds, selectLatestBuilds(builds, 0))
+	assert.Equal(t, builds, selectLatestBuilds(builds, 5))
+	assert.Equal(t, []buildListData{builds[1], builds[2]}, selectLatestBuilds(builds, 2))
+}
+
+func TestProjectQuery(t *testing.T) {
+	assert.Equal(t, "", projectQuery(""))
+	assert.Equal(t, "?project=acme", projectQuery("acme"))
+	assert.Equal(t, "?project=a%26b%3Dc", projectQuery("a&b=c"))
+}
+
+func TestFilterProjectRepos(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("project") != "acme" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`[{"key": "acme-docker-local"}, {"key": "acme-unindexed"}]`))
+	}))
+	defer server.Close()
+	serverConfig := &config.ServerDetails{ArtifactoryUrl: server.URL + "/"}
+	indexList := []IndexedRepo{{Name: "acme-docker-local", PkgType: "docker", Type: "local"}, {Name: "docker-local", PkgType: "docker", Type: "local"}}
+
+	repos, err := filterProjectRepos(indexList, "acme", serverConfig)
+	assert.NoError(t, err)
+	assert.Equal(t, indexList[:1], repos)
+
+	_, err = filterProjectRepos(indexList, "other", serverConfig)
+	assert.Error(t, err)
+}
+
+func TestReindexBody(t *testing.T) {
+	var request reindexRequest
+	build := queueDetails{Repo: "app", ScanType: "build", Project: `acme"}]}`, FileListData: helpers.Files{Uri: "42"}}
+	assert.NoError(t, json.Unmarshal([]byte(reindexBody(build)), &request))
+	assert.Equal(t, reindexRequest{Builds: []reindexBuild{{Name: "app", Number: "42", Project: `acme"}]}`}}}, request)
+
+	artifact := queueDetails{Repo: "generic-local", ScanType: "artifact", FileListData: helpers.Files{Uri: "/a.tar.gz"}}
+	assert.Equal(t, `{"artifacts":[{"repository":"generic-local","path":"/a.tar.gz"}]}`, reindexBody(artifact))
+	assert.Equal(t, "", reindexBody(queueDetails{ScanType: "releaseBundle"}))
+}
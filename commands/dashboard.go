@@ -0,0 +1,200 @@
+package commands
+
+import (
+	"strconv"
+	"time"
+
+	ui "github.com/gizak/termui/v3"
+	"github.com/gizak/termui/v3/widgets"
+	"github.com/jfrog/jfrog-client-go/utils/log"
+)
+
+//panelMinSize smallest outer size in cells a panel type is still readable at, borders included
+var panelMinSize = map[string][2]int{
+	"gauge": {10, 3},
+	"text":  {10, 3},
+	"list":  {10, 3},
+	"bar":   {10, 5},
+	"plot":  {20, 8},
+	"queue": {20, 4},
+}
+
+//dashboard the panels of a layout and the grid they are currently placed on.
+//Panels outlive the grid, so a resize keeps their history
+type dashboard struct {
+	root   *layoutNode
+	panels []dashboardPanel
+	byNode map[*panelConfig]dashboardPanel
+	tabs   map[string]*layoutNode //tabs by key
+	active map[*layoutNode]int    //tab shown of every tabs node
+	grid   *ui.Grid
+	hidden int
+	width  int
+	height int
+	last   *dashboardFrame //kept to show errors on
+}
+
+//newDashboard one panel per leaf of the layout
+func newDashboard(root *layoutNode, window time.Duration) *dashboard {
+	d := &dashboard{root: root, byNode: make(map[*panelConfig]dashboardPanel), tabs: make(map[string]*layoutNode), active: make(map[*layoutNode]int)}
+	addPanel := func(config *panelConfig) {
+		panel := newDashboardPanel(config, window)
+		d.panels = append(d.panels, panel)
+		d.byNode[config] = panel
+	}
+	var add func(node *layoutNode)
+	add = func(node *layoutNode) {
+		if node.Panel != nil {
+			addPanel(node.Panel)
+		}
+		for i := range node.Tabs {
+			addPanel(&node.Tabs[i])
+		}
+		if len(node.Tabs) > 0 {
+			d.tabs[node.Key] = node
+		}
+		for i := range node.Rows {
+			add(&node.Rows[i])
+		}
+		for i := range node.Columns {
+			add(&node.Columns[i])
+		}
+	}
+	add(root)
+	return d
+}
+
+//resize place the panels on a width x height grid. Panels that would be smaller than their minimum
+//size are left out and their siblings grow into the space
+func (d *dashboard) resize(width, height int) {
+	d.hidden, d.width, d.height = 0, width, height
+	d.grid = ui.NewGrid()
+	d.grid.SetRect(0, 0, width, height)
+	root := d.fit(d.root, float64(width), float64(height))
+	switch {
+	case root == nil:
+		//not even one panel fits, say so instead of drawing garbage
+		message := widgets.NewParagraph()
+		message.Border = false
+		message.Text = "Terminal too small (" + strconv.Itoa(width) + "x" + strconv.Itoa(height) + "), enlarge it or press q to quit"
+		d.grid.Set(ui.NewRow(1, message))
+	case root.Panel != nil:
+		d.grid.Set(ui.NewRow(1, d.byNode[root.Panel].drawable()))
+	default:
+		d.grid.Set(d.items(root)...)
+	}
+	if d.hidden > 0 {
+		log.Debug("terminal of", width, "x", height, "is too small for", d.hidden, "panels")
+	}
+}
+
+//fit a copy of node without the panels that do not fit in width x height, nil when none does
+func (d *dashboard) fit(node *layoutNode, width, height float64) *layoutNode {
+	if len(node.Tabs) > 0 {
+		//only the tab shown takes space
+		return d.fit(&layoutNode{Ratio: node.Ratio, Panel: &node.Tabs[d.active[node]]}, width, height)
+	}
+	if node.Panel != nil {
+		min := panelMinSize[node.Panel.Type]
+		if width < float64(min[0]) || height < float64(min[1]) {
+			d.hidden++
+			return nil
+		}
+		return node
+	}
+	children, column := node.Rows, false
+	if len(node.Columns) > 0 {
+		children, column = node.Columns, true
+	}
+	var kept []layoutNode
+	var total, keptTotal float64
+	for i := range children {
+		total += children[i].Ratio
+		childWidth, childHeight := width, height*children[i].Ratio
+		if column {
+			childWidth, childHeight = width*children[i].Ratio, height
+		}
+		if child := d.fit(&children[i], childWidth, childHeight); child != nil {
+			kept = append(kept, *child)
+			keptTotal += child.Ratio
+		}
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+	//siblings of dropped cells share their space in proportion to their own ratios
+	for i := range kept {
+		kept[i].Ratio *= total / keptTotal
+	}
+	fitted := &layoutNode{Ratio: node.Ratio}
+	if column {
+		fitted.Columns = kept
+	} else {
+		fitted.Rows = kept
+	}
+	return fitted
+}
+
+//items the grid rows or columns of a node
+func (d *dashboard) items(node *layoutNode) []interface{} {
+	var items []interface{}
+	add := func(child *layoutNode, column bool) {
+		entries := []interface{}{}
+		if child.Panel != nil {
+			entries = append(entries, d.byNode[child.Panel].drawable())
+		} else {
+			entries = d.items(child)
+		}
+		if column {
+			items = append(items, ui.NewCol(child.Ratio, entries...))
+		} else {
+			items = append(items, ui.NewRow(child.Ratio, entries...))
+		}
+	}
+	for i := range node.Rows {
+		add(&node.Rows[i], false)
+	}
+	for i := range node.Columns {
+		add(&node.Columns[i], true)
+	}
+	return items
+}
+
+//key show the next tab of the tabs bound to key, or pass it to the panel bound to it. False when none is
+func (d *dashboard) key(key string) bool {
+	if node, ok := d.tabs[key]; ok {
+		d.active[node] = (d.active[node] + 1) % len(node.Tabs)
+		d.resize(d.width, d.height)
+		return true
+	}
+	for _, panel := range d.panels {
+		if keyed, ok := panel.(keyedPanel); ok && keyed.key(key) {
+			return true
+		}
+	}
+	return false
+}
+
+//update every panel, including hidden ones and tabs that are not shown, so they keep their history
+func (d *dashboard) update(frame *dashboardFrame) {
+	for _, panel := range d.panels {
+		panel.update(frame)
+	}
+	d.last = frame
+}
+
+//showError render the text panels again on the last frame with the error, the other panels keep their data
+func (d *dashboard) showError(err error) {
+	frame := newDashboardFrame(nil)
+	if d.last != nil {
+		last := *d.last
+		last.Now = frame.Now
+		frame = &last
+	}
+	frame.Error = err.Error()
+	for _, panel := range d.panels {
+		if text, ok := panel.(*textPanel); ok {
+			text.update(frame)
+		}
+	}
+}
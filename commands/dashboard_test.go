@@ -0,0 +1,134 @@
+package commands
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	ui "github.com/gizak/termui/v3"
+	"github.com/gizak/termui/v3/widgets"
+	helpers "github.com/lorenyeung/indexcheck/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDashboardResize(t *testing.T) {
+	root, err := loadLayout("")
+	assert.NoError(t, err)
+	board := newDashboard(root, time.Minute)
+	frame := newDashboardFrame(testData())
+	frame.Interval = 1
+	board.update(frame)
+
+	//one of the tabs is never shown
+	for _, size := range []struct{ width, height, hidden, shown int }{{146, 56, 0, 11}, {80, 24, 6, 5}, {8, 2, 11, 1}} {
+		board.resize(size.width, size.height)
+		assert.Equal(t, size.hidden, board.hidden, size)
+		assert.Len(t, board.grid.Items, size.shown, size)
+		assert.NotPanics(t, func() { board.grid.Draw(ui.NewBuffer(board.grid.GetRect())) })
+	}
+	message, ok := board.grid.Items[0].Entry.(*widgets.Paragraph)
+	if assert.True(t, ok) {
+		assert.Contains(t, message.Text, "Terminal too small (8x2)")
+	}
+}
+
+func TestDashboardCollapse(t *testing.T) {
+	root, err := parseLayout([]byte(`
+columns:
+  - ratio: 0.7
+    panel: {type: text, text: hello}
+  - ratio: 0.3
+    panel: {type: plot, series: [{expr: up}]}
+`))
+	assert.NoError(t, err)
+	board := newDashboard(root, time.Minute)
+
+	board.resize(100, 20)
+	assert.Equal(t, 0, board.hidden)
+	assert.Len(t, board.grid.Items, 2)
+
+	//the plot would be 15 wide, so the text takes the whole width
+	board.resize(50, 20)
+	assert.Equal(t, 1, board.hidden)
+	if assert.Len(t, board.grid.Items, 1) {
+		assert.InDelta(t, 1, board.grid.Items[0].WidthRatio, 0.0001)
+	}
+}
+
+func TestDashboardTabs(t *testing.T) {
+	root, err := parseLayout([]byte(`
+rows:
+  - panel: {type: text, text: hello}
+  - key: t
+    tabs:
+      - {type: text, title: first, text: one}
+      - {type: plot, title: second, series: [{expr: app_disk_free_bytes}]}
+`))
+	assert.NoError(t, err)
+	board := newDashboard(root, time.Minute)
+	board.resize(100, 30)
+	tab := func() string {
+		switch entry := board.grid.Items[1].Entry.(type) {
+		case *widgets.Paragraph:
+			return entry.Title
+		case *plotPanel:
+			return entry.Title
+		}
+		return ""
+	}
+	assert.Equal(t, "first (t: next)", tab())
+
+	assert.False(t, board.key("x"))
+	assert.True(t, board.key("t"))
+	assert.Equal(t, "second (t: next), last 1m", tab())
+	assert.True(t, board.key("t"))
+	assert.Equal(t, "first (t: next)", tab())
+
+	//tabs that are not shown still keep their history
+	board.update(newDashboardFrame(testData()))
+	point, ok := board.panels[2].(*plotPanel).lines["app_disk_free_bytes"].last()
+	assert.True(t, ok)
+	assert.Equal(t, float64(50), point.Value)
+}
+
+//failingSource a source whose polls fail with err
+type failingSource struct {
+	err error
+}
+
+func (s failingSource) next(counter, interval int) ([]helpers.Data, string, int, error) {
+	return nil, "", 0, s.err
+}
+
+func (s failingSource) taken() time.Time { return time.Time{} }
+
+func (s failingSource) name() string { return "failing" }
+
+func TestDashboardShowError(t *testing.T) {
+	root, err := loadLayout("")
+	assert.NoError(t, err)
+	board := newDashboard(root, time.Minute)
+	frame := newDashboardFrame(testData())
+	frame.Interval, frame.Server = 1, "acme"
+	board.update(frame)
+	meta := board.panels[0].(*textPanel).paragraph
+
+	//a bad scrape is shown with its line number on top of the last data
+	parseErr := &helpers.MetricsParseError{Product: "xray", Line: 42, Text: "queue{", Msg: "unexpected end"}
+	_, err = drawFunction(failingSource{parseErr}, board, 0, 1, false)
+	assert.True(t, errors.Is(err, parseErr))
+	board.showError(err)
+	assert.Contains(t, meta.Text, "Server url: acme")
+	assert.Contains(t, meta.Text, "Poll failed, showing the last data: Failed to parse xray metrics on line 42")
+
+	//the next good poll clears it
+	board.update(frame)
+	assert.NotContains(t, meta.Text, "Poll failed")
+
+	//only a recording without snapshots ends the graph
+	_, err = drawFunction(newReplaySource(strings.NewReader(""), "empty.ndjson", 1), board, 0, 1, false)
+	var ended sourceEndedError
+	assert.True(t, errors.As(err, &ended))
+	assert.False(t, errors.As(parseErr, &ended))
+}
@@ -0,0 +1,151 @@
+package commands
+
+import (
+	"container/list"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/jfrog/jfrog-cli-core/v2/utils/config"
+	"github.com/jfrog/jfrog-client-go/utils/log"
+	helpers "github.com/lorenyeung/indexcheck/utils"
+)
+
+type buildInfo struct {
+	BuildInfo buildInfoData `json:"buildInfo"`
+}
+
+type buildInfoData struct {
+	Name    string        `json:"name"`
+	Number  string        `json:"number"`
+	Modules []buildModule `json:"modules"`
+}
+
+type buildModule struct {
+	Id           string      `json:"id"`
+	Artifacts    []buildFile `json:"artifacts"`
+	Dependencies []buildFile `json:"dependencies"`
+}
+
+//buildFile artifacts are identified by name, dependencies by id
+type buildFile struct {
+	Name   string `json:"name"`
+	Id     string `json:"id"`
+	Sha1   string `json:"sha1"`
+	Sha256 string `json:"sha256"`
+}
+
+func (f buildFile) displayName() string {
+	if f.Name != "" {
+		return f.Name
+	}
+	return f.Id
+}
+
+//deepCheckBuild check the scan status of every module artifact and dependency of a build number
+func deepCheckBuild(buildName, buildNumber, project string, indexedMap map[string]IndexedRepo, config *config.ServerDetails, opts *checkOptions) error {
+	buildInfoData, respCode, _ := helpers.GetRestAPI("GET", true, config.ArtifactoryUrl+"api/build/"+url.PathEscape(buildName)+"/"+url.PathEscape(buildNumber)+projectQuery(project), config, "", nil, 0)
+	if respCode != 200 {
+		return errors.New("Build info received unexpected response code:" + strconv.Itoa(respCode) + " :" + string(buildInfoData))
+	}
+	var buildInfoStruct buildInfo
+	err := json.Unmarshal(buildInfoData, &buildInfoStruct)
+	if err != nil {
+		return errors.New(err.Error() + " at " + string(helpers.Trace().Fn) + " on line " + string(strconv.Itoa(helpers.Trace().Line)))
+	}
+
+	fmt.Fprintln(opts.out, "checking modules of:"+buildName+" number:"+buildNumber)
+	for _, module := range buildInfoStruct.BuildInfo.Modules {
+		artifactCount, artifactNotIndex, artifactMissing := deepCheckFiles(module.Artifacts, indexedMap, config, opts)
+		dependencyCount, dependencyNotIndex, dependencyMissing := deepCheckFiles(module.Dependencies, indexedMap, config, opts)
+		fmt.Fprintln(opts.out, "Module "+module.Id+" artifacts scanned count:", artifactCount-artifactNotIndex, "/", artifactCount, " dependencies scanned count:", dependencyCount-dependencyNotIndex, "/", dependencyCount, " not found or not indexed:", artifactMissing+dependencyMissing)
+	}
+	return nil
+}
+
+//deepCheckFiles resolve build files to their repository path and check their scan status, files that cannot be resolved are only counted as missing
+func deepCheckFiles(files []buildFile, indexedMap map[string]IndexedRepo, config *config.ServerDetails, opts *checkOptions) (int, int, int) {
+	var totalCount, notIndexCount, missingCount int
+	seen := make(map[string]bool)
+	fileAnalysis := list.New()
+	for _, file := range files {
+		if file.Sha1 == "" && file.Sha256 == "" {
+			fmt.Fprintln(opts.out, fmt.Sprintf("%-19v", "no checksum"), "\t", file.displayName())
+			missingCount++
+			continue
+		}
+		if seen[file.Sha1+file.Sha256] {
+			continue
+		}
+		seen[file.Sha1+file.Sha256] = true
+
+		queueDetails, status := resolveBuildFile(file, indexedMap, config)
+		if status != "" {
+			fmt.Fprintln(opts.out, fmt.Sprintf("%-19v", status), "\t", file.displayName())
+			missingCount++
+			continue
+		}
+		fileAnalysis.PushBack(queueDetails)
+	}
+	totalCount, notIndexCount = workerPool(fileAnalysis, config, opts, totalCount, notIndexCount)
+	return totalCount, notIndexCount, missingCount
+}
+
+//resolveBuildFile find where a build file is stored, a non empty status explains why it can't be checked
+func resolveBuildFile(file buildFile, indexedMap map[string]IndexedRepo, config *config.ServerDetails) (queueDetails, string) {
+	var q queueDetails
+	uris, err := helpers.SearchChecksum(file.Sha1, file.Sha256, config)
+	if err != nil {
+		log.Warn(err)
+		return q, "Failed searching"
+	}
+	if len(uris) == 0 {
+		return q, "not found"
+	}
+
+	//prefer a copy that is stored in an indexed repository
+	for _, uri := range uris {
+		repo, path, ok := storageUriToRepoPath(uri)
+		if !ok {
+			log.Debug("Unexpected storage uri:", uri)
+			continue
+		}
+		indexed, ok := lookupIndexedRepo(repo, indexedMap)
+		if !ok {
+			continue
+		}
+		sha256 := file.Sha256
+		if sha256 == "" {
+			var fileInfo helpers.FileInfo
+			fileDetails, respCode, _ := helpers.GetRestAPI("GET", true, config.ArtifactoryUrl+"api/storage/"+repo+path, config, "", nil, 0)
+			if respCode == 200 {
+				json.Unmarshal(fileDetails, &fileInfo)
+			}
+			sha256 = fileInfo.Checksums.Sha256
+		}
+		q.Repo = repo
+		q.PkgType = resolveRepoType(indexed).PkgType
+		q.RepoType = resolveRepoType(indexed).Type
+		q.ScanType = "artifact"
+		q.FileListData = helpers.Files{Uri: path, Sha256: sha256}
+		return q, ""
+	}
+	return q, "repo not indexed"
+}
+
+//storageUriToRepoPath split a storage api uri into the repository and the path within it
+func storageUriToRepoPath(uri string) (string, string, bool) {
+	i := strings.Index(uri, "/api/storage/")
+	if i < 0 {
+		return "", "", false
+	}
+	repoPath := uri[i+len("/api/storage/"):]
+	j := strings.Index(repoPath, "/")
+	if j <= 0 {
+		return "", "", false
+	}
+	return repoPath[:j], repoPath[j:], true
+}
@@ -0,0 +1,115 @@
+package commands
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jfrog/jfrog-cli-core/v2/utils/config"
+	helpers "github.com/lorenyeung/indexcheck/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStorageUriToRepoPath(t *testing.T) {
+	repo, path, ok := storageUriToRepoPath("https://acme.jfrog.io/artifactory/api/storage/libs-release-local/org/acme/app/1.0/app-1.0.jar")
+	assert.True(t, ok)
+	assert.Equal(t, "libs-release-local", repo)
+	assert.Equal(t, "/org/acme/app/1.0/app-1.0.jar", path)
+
+	_, _, ok = storageUriToRepoPath("https://acme.jfrog.io/artifactory/libs-release-local/app.jar")
+	assert.False(t, ok)
+}
+
+//newDeepServer artifactory checksum search and storage, and an xray that reports everything but app.jar as scanned
+func newDeepServer() *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		storage := server.URL + "/artifactory/api/storage/"
+		switch r.URL.Path {
+		case "/artifactory/api/search/checksum":
+			switch r.URL.RawQuery {
+			case "sha1=app":
+				//the copy in the indexed repository is picked over the one that is not
+				w.Write([]byte(`{"results": [{"uri": "` + storage + `unindexed-local/app.jar"}, {"uri": "` + storage + `libs-local/org/app.jar"}]}`))
+			case "sha256=lib":
+				w.Write([]byte(`{"results": [{"uri": "` + storage + `maven-remote-cache/org/lib.jar"}]}`))
+			case "sha1=stray":
+				w.Write([]byte(`{"results": [{"uri": "` + storage + `unindexed-local/stray.jar"}]}`))
+			case "sha1=gone":
+				w.Write([]byte(`{"results": []}`))
+			default:
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		case "/artifactory/api/storage/libs-local/org/app.jar":
+			w.Write([]byte(`{"checksums": {"sha256": "app256"}}`))
+		case "/xray/api/v1/scan/status/artifact":
+			var body bytes.Buffer
+			body.ReadFrom(r.Body)
+			if bytes.Contains(body.Bytes(), []byte("app.jar")) {
+				w.Write([]byte(`{"status": "not indexed"}`))
+				return
+			}
+			w.Write([]byte(`{"status": "scanned"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	return server
+}
+
+func TestResolveBuildFile(t *testing.T) {
+	server := newDeepServer()
+	defer server.Close()
+	serverConfig := &config.ServerDetails{ArtifactoryUrl: server.URL + "/artifactory/", XrayUrl: server.URL + "/xray/"}
+	indexedMap := map[string]IndexedRepo{
+		"libs-local":   {Name: "libs-local", PkgType: "Maven", Type: "local"},
+		"maven-remote": {Name: "maven-remote", PkgType: "Maven", Type: "remote"},
+	}
+
+	q, status := resolveBuildFile(buildFile{Name: "app.jar", Sha1: "app"}, indexedMap, serverConfig)
+	assert.Equal(t, "", status)
+	assert.Equal(t, queueDetails{Repo: "libs-local", PkgType: "maven", RepoType: "local", ScanType: "artifact", FileListData: helpers.Files{Uri: "/org/app.jar", Sha256: "app256"}}, q)
+
+	//a remote cache resolves through its remote, the sha256 it was found by is kept
+	q, status = resolveBuildFile(buildFile{Id: "org:lib:1.0", Sha1: "lib1", Sha256: "lib"}, indexedMap, serverConfig)
+	assert.Equal(t, "", status)
+	assert.Equal(t, "maven-remote-cache", q.Repo)
+	assert.Equal(t, helpers.Files{Uri: "/org/lib.jar", Sha256: "lib"}, q.FileListData)
+
+	for sha1, expected := range map[string]string{"stray": "repo not indexed", "gone": "not found", "broken": "Failed searching"} {
+		_, status = resolveBuildFile(buildFile{Sha1: sha1}, indexedMap, serverConfig)
+		assert.Equal(t, expected, status, sha1)
+	}
+}
+
+func TestDeepCheckFiles(t *testing.T) {
+	server := newDeepServer()
+	defer server.Close()
+	serverConfig := &config.ServerDetails{ArtifactoryUrl: server.URL + "/artifactory/", XrayUrl: server.URL + "/xray/"}
+	indexedMap := map[string]IndexedRepo{
+		"libs-local":   {Name: "libs-local", PkgType: "Maven", Type: "local"},
+		"maven-remote": {Name: "maven-remote", PkgType: "Maven", Type: "remote"},
+	}
+	var out bytes.Buffer
+	opts := &checkOptions{workers: 1, out: &out, report: newCheckReport()}
+
+	files := []buildFile{
+		{Name: "app.jar", Sha1: "app"},
+		{Name: "app-copy.jar", Sha1: "app"},
+		{Id: "org:lib:1.0", Sha256: "lib"},
+		{Id: "org:stray:1.0", Sha1: "stray"},
+		{Id: "org:gone:1.0", Sha1: "gone"},
+		{Id: "org:unsigned:1.0"},
+	}
+	total, notIndex, missing := deepCheckFiles(files, indexedMap, serverConfig, opts)
+	//the copy of app.jar is only checked once
+	assert.Equal(t, 2, total)
+	assert.Equal(t, 1, notIndex)
+	assert.Equal(t, 3, missing)
+	assert.Contains(t, out.String(), "no checksum")
+	assert.Contains(t, out.String(), "org:unsigned:1.0")
+	assert.Contains(t, out.String(), "repo not indexed")
+	assert.Contains(t, out.String(), "org:stray:1.0")
+	assert.Len(t, opts.report.Statuses(), 2)
+}
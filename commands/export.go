@@ -0,0 +1,196 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jfrog/jfrog-cli-core/v2/plugins/components"
+	"github.com/jfrog/jfrog-cli-core/v2/utils/config"
+	"github.com/jfrog/jfrog-client-go/utils/log"
+	helpers "github.com/lorenyeung/indexcheck/utils"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+func GetExportCommand() components.Command {
+	return components.Command{
+		Name:        "export",
+		Description: "Export index coverage as Prometheus metrics.",
+		Aliases:     []string{"e"},
+		Arguments:   getCheckArguments(),
+		Flags:       getExportFlags(),
+		EnvVars:     getExportEnvVar(),
+		Action: func(c *components.Context) error {
+			return ExportCmd(c)
+		},
+	}
+}
+
+//getExportFlags the check run flags, watch and notify do not apply to a check that runs on a schedule
+func getExportFlags() []components.Flag {
+	return append(getCheckRunFlags(),
+		components.StringFlag{
+			Name:         "listen",
+			Description:  "Address to serve /metrics on",
+			DefaultValue: ":9400",
+		},
+		components.StringFlag{
+			Name:         "interval",
+			Description:  "time between checks e.g. 30m",
+			DefaultValue: "5m",
+		},
+	)
+}
+
+func getExportEnvVar() []components.EnvVar {
+	return []components.EnvVar{}
+}
+
+//exporter latest index coverage, in Prometheus metric families
+type exporter struct {
+	mu       sync.Mutex
+	families []*dto.MetricFamily
+}
+
+func ExportCmd(c *components.Context) error {
+	config, err := helpers.GetConfig(helpers.MetricsXray)
+	if err != nil {
+		return err
+	}
+	if len(c.Arguments) == 0 {
+		return errors.New("Please provide appropiate arguments")
+	}
+	opts, err := getCheckOptions(c)
+	if err != nil {
+		return err
+	}
+	interval, err := time.ParseDuration(c.GetStringFlagValue("interval"))
+	if err != nil || interval <= 0 {
+		return errors.New("Invalid interval value:" + c.GetStringFlagValue("interval"))
+	}
+
+	e := new(exporter)
+	go e.run(c.Arguments, interval, config, opts)
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", e)
+	fmt.Println("Serving index coverage on", c.GetStringFlagValue("listen")+"/metrics", "checking every", interval)
+	return http.ListenAndServe(c.GetStringFlagValue("listen"), mux)
+}
+
+//run check on the interval and swap in the new families once each check is done
+func (e *exporter) run(args []string, interval time.Duration, config *config.ServerDetails, opts *checkOptions) {
+	run := opts.rerun(ioutil.Discard)
+	for {
+		timeStart := time.Now()
+		err := runChecks(args, config, run)
+		duration := time.Since(timeStart)
+		if err != nil {
+			log.Warn("Export check failed:", err)
+		}
+		e.mu.Lock()
+		e.families = exportFamilies(run.report, duration, err == nil, time.Now())
+		e.mu.Unlock()
+		log.Debug("Export check took:", duration)
+
+		time.Sleep(interval)
+		//only the first run may reindex, same as watch mode
+		run = run.rerun(ioutil.Discard)
+		run.reindex = false
+	}
+}
+
+func (e *exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.mu.Lock()
+	families := e.families
+	e.mu.Unlock()
+	format := expfmt.Negotiate(r.Header)
+	w.Header().Set("Content-Type", string(format))
+	encoder := expfmt.NewEncoder(w, format)
+	for _, family := range families {
+		if err := encoder.Encode(family); err != nil {
+			log.Warn("Failed to encode metrics:", err)
+			return
+		}
+	}
+}
+
+//exportFamilies turn a check report into gauges
+func exportFamilies(report *checkReport, duration time.Duration, success bool, now time.Time) []*dto.MetricFamily {
+	artifacts := newGaugeFamily("indexcheck_artifacts_total", "Artifacts checked, by repository and scan status")
+	statusCounts := make(map[[2]string]int)
+	for _, status := range report.Statuses() {
+		statusCounts[[2]string{status.Repo, status.Status}]++
+	}
+	for key, count := range statusCounts {
+		artifacts.Metric = append(artifacts.Metric, newGauge(float64(count), "repo", key[0], "status", key[1]))
+	}
+
+	unindexable := newGaugeFamily("indexcheck_unindexable_total", "Files that Xray can not index, by repository and file extension")
+	emptyCache := newGaugeFamily("indexcheck_remote_cache_empty", "Remote repositories with nothing cached")
+	for _, repo := range report.Repos() {
+		for ext, count := range repo.UnindexableMap {
+			unindexable.Metric = append(unindexable.Metric, newGauge(float64(count), "repo", repo.Repo, "ext", ext))
+		}
+		if repo.EmptyCache {
+			emptyCache.Metric = append(emptyCache.Metric, newGauge(1, "repo", repo.Repo))
+		}
+	}
+
+	checkDuration := newGaugeFamily("indexcheck_check_duration_seconds", "Duration of the last check")
+	checkDuration.Metric = append(checkDuration.Metric, newGauge(duration.Seconds()))
+	var successValue float64
+	if success {
+		successValue = 1
+	}
+	checkSuccess := newGaugeFamily("indexcheck_check_success", "Whether the last check completed without error")
+	checkSuccess.Metric = append(checkSuccess.Metric, newGauge(successValue))
+	lastCheck := newGaugeFamily("indexcheck_last_check_timestamp_seconds", "Unix time the last check finished")
+	lastCheck.Metric = append(lastCheck.Metric, newGauge(float64(now.Unix())))
+
+	var families []*dto.MetricFamily
+	for _, family := range []*dto.MetricFamily{artifacts, unindexable, emptyCache, checkDuration, checkSuccess, lastCheck} {
+		//the text format does not allow families without metrics
+		if len(family.Metric) == 0 {
+			continue
+		}
+		sortMetrics(family.Metric)
+		families = append(families, family)
+	}
+	return families
+}
+
+func newGaugeFamily(name, help string) *dto.MetricFamily {
+	gauge := dto.MetricType_GAUGE
+	return &dto.MetricFamily{Name: &name, Help: &help, Type: &gauge}
+}
+
+//newGauge gauge with label name and value pairs
+func newGauge(value float64, labels ...string) *dto.Metric {
+	metric := &dto.Metric{Gauge: &dto.Gauge{Value: &value}}
+	for i := 0; i+1 < len(labels); i += 2 {
+		name, labelValue := labels[i], labels[i+1]
+		metric.Label = append(metric.Label, &dto.LabelPair{Name: &name, Value: &labelValue})
+	}
+	return metric
+}
+
+//sortMetrics stable output order, by label values
+func sortMetrics(metrics []*dto.Metric) {
+	sort.Slice(metrics, func(i, j int) bool {
+		return labelKey(metrics[i]) < labelKey(metrics[j])
+	})
+}
+
+func labelKey(metric *dto.Metric) string {
+	var key string
+	for _, label := range metric.Label {
+		key += label.GetValue() + "\x00"
+	}
+	return key
+}
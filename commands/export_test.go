@@ -0,0 +1,51 @@
+package commands
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/expfmt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportFamilies(t *testing.T) {
+	report := testReport()
+	report.addRepo(repoResult{Repo: "docker-local", UnindexableMap: map[string]int{".txt": 4}})
+	report.addRepo(repoResult{Repo: "npm-remote-cache", EmptyCache: true})
+
+	var out bytes.Buffer
+	encoder := expfmt.NewEncoder(&out, expfmt.FmtText)
+	for _, family := range exportFamilies(report, 1500*time.Millisecond, true, time.Unix(1638862071, 0)) {
+		assert.NoError(t, encoder.Encode(family))
+	}
+	text := out.String()
+	assert.Contains(t, text, `indexcheck_artifacts_total{repo="generic-local",status="failed"} 1`)
+	assert.Contains(t, text, `indexcheck_artifacts_total{repo="generic-local",status="scanned"} 1`)
+	assert.Contains(t, text, `indexcheck_unindexable_total{repo="docker-local",ext=".txt"} 4`)
+	assert.Contains(t, text, `indexcheck_remote_cache_empty{repo="npm-remote-cache"} 1`)
+	assert.Contains(t, text, "indexcheck_check_duration_seconds 1.5")
+	assert.Contains(t, text, "indexcheck_check_success 1")
+	assert.Contains(t, text, "indexcheck_last_check_timestamp_seconds 1.638862071e+09")
+}
+
+func TestExportFamiliesSkipsEmpty(t *testing.T) {
+	families := exportFamilies(newCheckReport(), time.Second, false, time.Now())
+	assert.Len(t, families, 3)
+}
+
+func TestExportFlags(t *testing.T) {
+	names := make(map[string]bool)
+	for _, flag := range getExportFlags() {
+		assert.False(t, names[flag.GetName()], flag.GetName())
+		names[flag.GetName()] = true
+	}
+	for _, flag := range getCheckRunFlags() {
+		assert.True(t, names[flag.GetName()], flag.GetName())
+	}
+	for _, flag := range getNotifyFlags() {
+		assert.False(t, names[flag.GetName()], flag.GetName())
+	}
+	assert.False(t, names["watch"])
+	assert.False(t, names["showall"])
+}
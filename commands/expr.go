@@ -0,0 +1,598 @@
+package commands
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	helpers "github.com/lorenyeung/indexcheck/utils"
+)
+
+//exprSample one series of an expression result
+type exprSample struct {
+	Name   string
+	Labels map[string]string
+	Value  float64
+}
+
+func (s exprSample) String() string {
+	if len(s.Labels) == 0 {
+		return s.Name
+	}
+	return s.Name + "{" + formatLabels(s.Labels) + "}"
+}
+
+//exprValue either a single number or a set of series
+type exprValue struct {
+	Scalar  bool
+	Samples []exprSample
+}
+
+//exprSeries every series of a snapshot by name, histograms and summaries as _bucket, _sum and _count series
+type exprSeries map[string][]exprSample
+
+func newExprSeries(data []helpers.Data) exprSeries {
+	series := make(exprSeries)
+	add := func(name string, labels map[string]string, value string) {
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return
+		}
+		series[name] = append(series[name], exprSample{Name: name, Labels: labels, Value: f})
+	}
+	for _, family := range data {
+		for _, metric := range family.Metric {
+			switch {
+			case metric.Buckets != nil:
+				for bound, count := range metric.Buckets {
+					add(family.Name+"_bucket", withLabel(metric.Labels, "le", bound), count)
+				}
+			case metric.Quantiles != nil:
+				for quantile, value := range metric.Quantiles {
+					add(family.Name, withLabel(metric.Labels, "quantile", quantile), value)
+				}
+			default:
+				add(family.Name, metric.Labels, metric.Value)
+				continue
+			}
+			add(family.Name+"_sum", metric.Labels, metric.Sum)
+			add(family.Name+"_count", metric.Labels, metric.Count)
+		}
+	}
+	return series
+}
+
+//exprNode a parsed expression
+type exprNode interface {
+	eval(series exprSeries) (exprValue, error)
+}
+
+type numberNode struct {
+	Value float64
+}
+
+func (n numberNode) eval(series exprSeries) (exprValue, error) {
+	return exprValue{Scalar: true, Samples: []exprSample{{Value: n.Value}}}, nil
+}
+
+type selectorNode struct {
+	Name     string
+	Matchers []labelMatcher
+}
+
+func (n selectorNode) eval(series exprSeries) (exprValue, error) {
+	result := exprValue{}
+	for _, sample := range series[n.Name] {
+		matched := true
+		for _, matcher := range n.Matchers {
+			if !matcher.matches(sample.Labels) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			result.Samples = append(result.Samples, sample)
+		}
+	}
+	sort.Slice(result.Samples, func(i, j int) bool {
+		return formatLabels(result.Samples[i].Labels) < formatLabels(result.Samples[j].Labels)
+	})
+	return result, nil
+}
+
+type negNode struct {
+	Expr exprNode
+}
+
+func (n negNode) eval(series exprSeries) (exprValue, error) {
+	value, err := n.Expr.eval(series)
+	if err != nil {
+		return exprValue{}, err
+	}
+	result := exprValue{Scalar: value.Scalar}
+	for _, sample := range value.Samples {
+		result.Samples = append(result.Samples, exprSample{Labels: sample.Labels, Value: -sample.Value})
+	}
+	return result, nil
+}
+
+//binaryNode arithmetic and comparisons. Series on both sides are matched on identical labels,
+//comparisons keep the left hand series that match instead of returning true or false
+type binaryNode struct {
+	Op  string
+	LHS exprNode
+	RHS exprNode
+}
+
+func isComparison(op string) bool {
+	switch op {
+	case ">", "<", ">=", "<=", "==", "!=":
+		return true
+	}
+	return false
+}
+
+func applyOp(op string, a, b float64) (float64, bool) {
+	switch op {
+	case "+":
+		return a + b, true
+	case "-":
+		return a - b, true
+	case "*":
+		return a * b, true
+	case "/":
+		return a / b, true
+	case ">":
+		return a, a > b
+	case "<":
+		return a, a < b
+	case ">=":
+		return a, a >= b
+	case "<=":
+		return a, a <= b
+	case "==":
+		return a, a == b
+	default:
+		return a, a != b
+	}
+}
+
+func (n binaryNode) eval(series exprSeries) (exprValue, error) {
+	lhs, err := n.LHS.eval(series)
+	if err != nil {
+		return exprValue{}, err
+	}
+	rhs, err := n.RHS.eval(series)
+	if err != nil {
+		return exprValue{}, err
+	}
+	comparison := isComparison(n.Op)
+	result := exprValue{Scalar: lhs.Scalar && rhs.Scalar}
+	combine := func(left, right exprSample, labels map[string]string) {
+		value, keep := applyOp(n.Op, left.Value, right.Value)
+		if !keep {
+			return
+		}
+		sample := exprSample{Labels: labels, Value: value}
+		if comparison {
+			//a comparison keeps the series it filtered, whichever side it is on
+			vector := left
+			if lhs.Scalar {
+				vector = right
+			}
+			sample.Name, sample.Value = vector.Name, vector.Value
+		}
+		result.Samples = append(result.Samples, sample)
+	}
+
+	switch {
+	case lhs.Scalar && rhs.Scalar:
+		if comparison {
+			//a comparison of two numbers is 1 when true, 0 when false
+			_, keep := applyOp(n.Op, lhs.Samples[0].Value, rhs.Samples[0].Value)
+			result.Samples = []exprSample{{}}
+			if keep {
+				result.Samples[0].Value = 1
+			}
+			return result, nil
+		}
+		combine(lhs.Samples[0], rhs.Samples[0], nil)
+	case rhs.Scalar:
+		for _, left := range lhs.Samples {
+			combine(left, rhs.Samples[0], left.Labels)
+		}
+	case lhs.Scalar:
+		for _, right := range rhs.Samples {
+			combine(lhs.Samples[0], right, right.Labels)
+		}
+	default:
+		rightByLabels := make(map[string]exprSample, len(rhs.Samples))
+		for _, right := range rhs.Samples {
+			rightByLabels[formatLabels(right.Labels)] = right
+		}
+		for _, left := range lhs.Samples {
+			if right, ok := rightByLabels[formatLabels(left.Labels)]; ok {
+				combine(left, right, left.Labels)
+			}
+		}
+	}
+	return result, nil
+}
+
+//aggregateNode sum, avg, max, min or count of the series, grouped by the By labels
+type aggregateNode struct {
+	Op   string
+	By   []string
+	Expr exprNode
+}
+
+var exprAggregations = map[string]bool{"sum": true, "avg": true, "max": true, "min": true, "count": true}
+
+func (n aggregateNode) eval(series exprSeries) (exprValue, error) {
+	value, err := n.Expr.eval(series)
+	if err != nil {
+		return exprValue{}, err
+	}
+	if value.Scalar {
+		return exprValue{}, errors.New(n.Op + " needs series, not a number")
+	}
+	type group struct {
+		labels map[string]string
+		values []float64
+	}
+	groups := make(map[string]*group)
+	var keys []string
+	for _, sample := range value.Samples {
+		labels := make(map[string]string)
+		for _, name := range n.By {
+			if v, ok := sample.Labels[name]; ok {
+				labels[name] = v
+			}
+		}
+		key := formatLabels(labels)
+		if groups[key] == nil {
+			groups[key] = &group{labels: labels}
+			keys = append(keys, key)
+		}
+		groups[key].values = append(groups[key].values, sample.Value)
+	}
+	sort.Strings(keys)
+
+	result := exprValue{}
+	for _, key := range keys {
+		g := groups[key]
+		aggregate := g.values[0]
+		switch n.Op {
+		case "sum", "avg":
+			for _, v := range g.values[1:] {
+				aggregate += v
+			}
+			if n.Op == "avg" {
+				aggregate /= float64(len(g.values))
+			}
+		case "max":
+			for _, v := range g.values[1:] {
+				if v > aggregate {
+					aggregate = v
+				}
+			}
+		case "min":
+			for _, v := range g.values[1:] {
+				if v < aggregate {
+					aggregate = v
+				}
+			}
+		case "count":
+			aggregate = float64(len(g.values))
+		}
+		result.Samples = append(result.Samples, exprSample{Labels: g.labels, Value: aggregate})
+	}
+	return result, nil
+}
+
+//exprToken a lexical token, Kind is ident, number, string or the operator itself
+type exprToken struct {
+	Kind  string
+	Value string
+	Pos   int
+}
+
+var exprOperators = []string{">=", "<=", "==", "!=", "=~", "!~", ">", "<", "=", "+", "-", "*", "/", "(", ")", "{", "}", ","}
+
+func tokenizeExpr(input string) ([]exprToken, error) {
+	var tokens []exprToken
+	for i := 0; i < len(input); {
+		c := rune(input[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '_' || c == ':' || unicode.IsLetter(c):
+			start := i
+			for i < len(input) && (input[i] == '_' || input[i] == ':' || unicode.IsLetter(rune(input[i])) || unicode.IsDigit(rune(input[i]))) {
+				i++
+			}
+			tokens = append(tokens, exprToken{Kind: "ident", Value: input[start:i], Pos: start})
+		case unicode.IsDigit(c) || c == '.':
+			start := i
+			for i < len(input) && (unicode.IsDigit(rune(input[i])) || input[i] == '.' || input[i] == 'e' || input[i] == 'E' ||
+				((input[i] == '+' || input[i] == '-') && (input[i-1] == 'e' || input[i-1] == 'E'))) {
+				i++
+			}
+			tokens = append(tokens, exprToken{Kind: "number", Value: input[start:i], Pos: start})
+		case c == '"' || c == '\'':
+			start := i
+			i++
+			for i < len(input) && rune(input[i]) != c {
+				if input[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			if i >= len(input) {
+				return nil, errors.New("unterminated string at position " + strconv.Itoa(start))
+			}
+			i++
+			quoted := input[start:i]
+			if c == '\'' {
+				quoted = strconv.Quote(strings.ReplaceAll(quoted[1:len(quoted)-1], `\'`, `'`))
+			}
+			value, err := strconv.Unquote(quoted)
+			if err != nil {
+				return nil, errors.New("invalid string at position " + strconv.Itoa(start) + ":" + err.Error())
+			}
+			tokens = append(tokens, exprToken{Kind: "string", Value: value, Pos: start})
+		default:
+			matched := false
+			for _, op := range exprOperators {
+				if strings.HasPrefix(input[i:], op) {
+					tokens = append(tokens, exprToken{Kind: op, Value: op, Pos: i})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, errors.New("unexpected character " + strconv.QuoteRune(c) + " at position " + strconv.Itoa(i))
+			}
+		}
+	}
+	return tokens, nil
+}
+
+//exprParser recursive descent, lowest precedence first: comparison, + -, * /, unary minus, then aggregations, selectors and numbers
+type exprParser struct {
+	tokens []exprToken
+	pos    int
+}
+
+//parseExpr parse a PromQL like expression, e.g. app_disk_free_bytes / app_disk_total_bytes < 0.1
+func parseExpr(input string) (exprNode, error) {
+	tokens, err := tokenizeExpr(input)
+	if err != nil {
+		return nil, errors.New("Invalid expression " + input + ": " + err.Error())
+	}
+	p := &exprParser{tokens: tokens}
+	node, err := p.parseComparison()
+	if err == nil && p.pos < len(p.tokens) {
+		err = p.errorf("unexpected " + p.tokens[p.pos].Value)
+	}
+	if err != nil {
+		return nil, errors.New("Invalid expression " + input + ": " + err.Error())
+	}
+	return node, nil
+}
+
+func (p *exprParser) peek() string {
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+	return p.tokens[p.pos].Kind
+}
+
+func (p *exprParser) errorf(msg string) error {
+	if p.pos >= len(p.tokens) {
+		return errors.New(msg + " at end of expression")
+	}
+	return errors.New(msg + " at position " + strconv.Itoa(p.tokens[p.pos].Pos))
+}
+
+func (p *exprParser) expect(kind string) (exprToken, error) {
+	if p.peek() != kind {
+		if p.pos >= len(p.tokens) {
+			return exprToken{}, p.errorf("expected " + kind)
+		}
+		return exprToken{}, p.errorf("expected " + kind + " but found " + p.tokens[p.pos].Value)
+	}
+	p.pos++
+	return p.tokens[p.pos-1], nil
+}
+
+func (p *exprParser) parseComparison() (exprNode, error) {
+	lhs, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	if op := p.peek(); isComparison(op) {
+		p.pos++
+		rhs, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		return binaryNode{Op: op, LHS: lhs, RHS: rhs}, nil
+	}
+	return lhs, nil
+}
+
+func (p *exprParser) parseAdditive() (exprNode, error) {
+	lhs, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for op := p.peek(); op == "+" || op == "-"; op = p.peek() {
+		p.pos++
+		rhs, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		lhs = binaryNode{Op: op, LHS: lhs, RHS: rhs}
+	}
+	return lhs, nil
+}
+
+func (p *exprParser) parseMultiplicative() (exprNode, error) {
+	lhs, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for op := p.peek(); op == "*" || op == "/"; op = p.peek() {
+		p.pos++
+		rhs, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		lhs = binaryNode{Op: op, LHS: lhs, RHS: rhs}
+	}
+	return lhs, nil
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if p.peek() == "-" {
+		p.pos++
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return negNode{Expr: node}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	switch p.peek() {
+	case "number":
+		token := p.tokens[p.pos]
+		value, err := strconv.ParseFloat(token.Value, 64)
+		if err != nil {
+			return nil, p.errorf("invalid number " + token.Value)
+		}
+		p.pos++
+		return numberNode{Value: value}, nil
+	case "(":
+		p.pos++
+		node, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return node, nil
+	case "ident":
+		if exprAggregations[p.tokens[p.pos].Value] && p.pos+1 < len(p.tokens) && (p.tokens[p.pos+1].Kind == "(" || p.tokens[p.pos+1].Value == "by") {
+			return p.parseAggregation()
+		}
+		return p.parseSelector()
+	case "":
+		return nil, p.errorf("expected a metric, number or (")
+	}
+	return nil, p.errorf("unexpected " + p.tokens[p.pos].Value)
+}
+
+//parseAggregation sum by (label) (expr) or sum(expr) by (label)
+func (p *exprParser) parseAggregation() (exprNode, error) {
+	node := aggregateNode{Op: p.tokens[p.pos].Value}
+	p.pos++
+	var err error
+	if p.peek() == "ident" && p.tokens[p.pos].Value == "by" {
+		if node.By, err = p.parseBy(); err != nil {
+			return nil, err
+		}
+	}
+	if _, err = p.expect("("); err != nil {
+		return nil, err
+	}
+	if node.Expr, err = p.parseComparison(); err != nil {
+		return nil, err
+	}
+	if _, err = p.expect(")"); err != nil {
+		return nil, err
+	}
+	if node.By == nil && p.peek() == "ident" && p.tokens[p.pos].Value == "by" {
+		if node.By, err = p.parseBy(); err != nil {
+			return nil, err
+		}
+	}
+	return node, nil
+}
+
+//parseBy by (label, label)
+func (p *exprParser) parseBy() ([]string, error) {
+	p.pos++
+	if _, err := p.expect("("); err != nil {
+		return nil, err
+	}
+	labels := []string{}
+	for p.peek() != ")" {
+		label, err := p.expect("ident")
+		if err != nil {
+			return nil, err
+		}
+		labels = append(labels, label.Value)
+		if p.peek() != "," {
+			break
+		}
+		p.pos++
+	}
+	if _, err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	return labels, nil
+}
+
+//parseSelector name{label="value", label=~"regex"}
+func (p *exprParser) parseSelector() (exprNode, error) {
+	name := p.tokens[p.pos].Value
+	p.pos++
+	selector := selectorNode{Name: name}
+	if p.peek() != "{" {
+		return selector, nil
+	}
+	p.pos++
+	for p.peek() != "}" {
+		label, err := p.expect("ident")
+		if err != nil {
+			return nil, err
+		}
+		op := p.peek()
+		switch op {
+		case "=", "!=", "=~", "!~":
+			p.pos++
+		default:
+			return nil, p.errorf("expected =, !=, =~ or !~")
+		}
+		value, err := p.expect("string")
+		if err != nil {
+			return nil, err
+		}
+		matcher, err := newLabelMatcher(label.Value, op, value.Value)
+		if err != nil {
+			return nil, err
+		}
+		selector.Matchers = append(selector.Matchers, matcher)
+		if p.peek() != "," {
+			break
+		}
+		p.pos++
+	}
+	if _, err := p.expect("}"); err != nil {
+		return nil, err
+	}
+	return selector, nil
+}
+
+//evalExpr evaluate a parsed expression against a snapshot
+func evalExpr(node exprNode, data []helpers.Data) (exprValue, error) {
+	return node.eval(newExprSeries(data))
+}
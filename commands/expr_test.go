@@ -0,0 +1,107 @@
+package commands
+
+import (
+	"bytes"
+	"testing"
+
+	helpers "github.com/lorenyeung/indexcheck/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func testData() []helpers.Data {
+	return []helpers.Data{
+		{Name: "app_disk_free_bytes", Type: "GAUGE", Metric: []helpers.Metrics{{Value: "50"}}},
+		{Name: "app_disk_total_bytes", Type: "GAUGE", Metric: []helpers.Metrics{{Value: "1000"}}},
+		{Name: "queue_messages_total", Type: "GAUGE", Metric: []helpers.Metrics{
+			{Value: "12000", Labels: map[string]string{"queue_name": "Index"}},
+			{Value: "3", Labels: map[string]string{"queue_name": "IndexRetry"}},
+			{Value: "40", Labels: map[string]string{"queue_name": "Persist"}},
+		}},
+		{Name: "jfxr_db_sync_duration_seconds", Type: "SUMMARY", Metric: []helpers.Metrics{
+			{Quantiles: map[string]string{"0.5": "2"}, Sum: "30", Count: "10"},
+		}},
+	}
+}
+
+func evalTest(t *testing.T, input string) exprValue {
+	node, err := parseExpr(input)
+	assert.NoError(t, err)
+	value, err := evalExpr(node, testData())
+	assert.NoError(t, err)
+	return value
+}
+
+func TestExprSelectors(t *testing.T) {
+	value := evalTest(t, `queue_messages_total{queue_name="Index"}`)
+	assert.Len(t, value.Samples, 1)
+	assert.Equal(t, float64(12000), value.Samples[0].Value)
+
+	value = evalTest(t, `queue_messages_total{queue_name=~"Index.*", queue_name!~'.*Retry'}`)
+	assert.Len(t, value.Samples, 1)
+
+	value = evalTest(t, `jfxr_db_sync_duration_seconds_sum / jfxr_db_sync_duration_seconds_count`)
+	assert.Equal(t, float64(3), value.Samples[0].Value)
+
+	assert.Empty(t, evalTest(t, `missing_metric`).Samples)
+}
+
+func TestExprArithmeticAndComparison(t *testing.T) {
+	value := evalTest(t, `app_disk_free_bytes / app_disk_total_bytes < 0.1`)
+	assert.Len(t, value.Samples, 1)
+	assert.Equal(t, 0.05, value.Samples[0].Value)
+
+	value = evalTest(t, `queue_messages_total > 10 * 2 + 1`)
+	assert.Len(t, value.Samples, 2)
+	assert.Equal(t, "queue_messages_total", value.Samples[0].Name)
+
+	value = evalTest(t, `-(1 + 2) * 2 == -6`)
+	assert.True(t, value.Scalar)
+	assert.Equal(t, float64(1), value.Samples[0].Value)
+
+	//series only match when their labels do
+	assert.Empty(t, evalTest(t, `queue_messages_total - app_disk_free_bytes`).Samples)
+
+	//the side the number is on does not change the result
+	assert.Equal(t, evalTest(t, `queue_messages_total * 2`), evalTest(t, `2 * queue_messages_total`))
+	assert.Equal(t, "", evalTest(t, `2 * queue_messages_total`).Samples[0].Name)
+	assert.Equal(t, evalTest(t, `queue_messages_total > 20`), evalTest(t, `20 < queue_messages_total`))
+	assert.Equal(t, float64(12000), evalTest(t, `20 < queue_messages_total`).Samples[0].Value)
+}
+
+func TestExprInvalid(t *testing.T) {
+	for _, input := range []string{"", "queue_messages_total >", `queue_messages_total{queue_name="Index"`, `queue_messages_total{queue_name=Index}`, "(1 + 2", "1 $ 2", `x{a=~"("}`} {
+		_, err := parseExpr(input)
+		assert.Error(t, err, input)
+	}
+}
+
+func TestExprAggregations(t *testing.T) {
+	value := evalTest(t, `sum by (queue_name) (queue_messages_total)`)
+	assert.Len(t, value.Samples, 3)
+	assert.Equal(t, "Index", value.Samples[0].Labels["queue_name"])
+
+	value = evalTest(t, `sum(queue_messages_total)`)
+	assert.Len(t, value.Samples, 1)
+	assert.Equal(t, float64(12043), value.Samples[0].Value)
+
+	value = evalTest(t, `max(queue_messages_total{queue_name!="Index"}) by (product)`)
+	assert.Equal(t, float64(40), value.Samples[0].Value)
+	assert.Empty(t, value.Samples[0].Labels)
+
+	assert.Equal(t, float64(3), evalTest(t, `min(queue_messages_total)`).Samples[0].Value)
+	assert.Equal(t, float64(3), evalTest(t, `count(queue_messages_total)`).Samples[0].Value)
+	assert.Equal(t, float64(22), evalTest(t, `avg(queue_messages_total{queue_name!="Index"}) + 0.5`).Samples[0].Value)
+
+	//a metric may still be called sum
+	_, err := parseExpr(`sum + 1`)
+	assert.NoError(t, err)
+	_, err = parseExpr(`sum by queue_name (queue_messages_total)`)
+	assert.Error(t, err)
+}
+
+func TestWriteQueryResult(t *testing.T) {
+	var out bytes.Buffer
+	value := evalTest(t, `sum by (queue_name) (queue_messages_total) / 0`)
+	assert.NoError(t, writeQueryResult(&out, value, &MetricsConfiguration{min: true}))
+	assert.Equal(t, `[{"labels":{"queue_name":"Index"},"value":"+Inf"},{"labels":{"queue_name":"IndexRetry"},"value":"+Inf"},{"labels":{"queue_name":"Persist"},"value":"+Inf"}]`+"\n", out.String())
+}
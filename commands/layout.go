@@ -0,0 +1,285 @@
+package commands
+
+import (
+	_ "embed"
+	"errors"
+	"io/ioutil"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+
+	ui "github.com/gizak/termui/v3"
+	"gopkg.in/yaml.v2"
+)
+
+//defaultLayout the dashboard graph draws without --layout
+//go:embed layouts/default.yaml
+var defaultLayout []byte
+
+//layoutNode a cell of the dashboard grid, split into rows or columns, holding a single panel,
+//or tabs of panels that Key switches between. Ratio is the share of the parent, siblings without one split what is left equally
+type layoutNode struct {
+	Ratio   float64       `yaml:"ratio"`
+	Rows    []layoutNode  `yaml:"rows"`
+	Columns []layoutNode  `yaml:"columns"`
+	Panel   *panelConfig  `yaml:"panel"`
+	Tabs    []panelConfig `yaml:"tabs"`
+	Key     string        `yaml:"key"`
+}
+
+//panelConfig a widget and the expressions bound to it
+type panelConfig struct {
+	Type   string         `yaml:"type"` //gauge, plot, bar, list, queue or text
+	Title  string         `yaml:"title"`
+	Color  string         `yaml:"color"`
+	Expr   string         `yaml:"expr"`   //gauge percentage, list and queue rows
+	Label  string         `yaml:"label"`  //list and queue: label shown for every row, all labels when empty
+	Hide   string         `yaml:"hide"`   //list and queue: rows matching this regex are only shown with --retry
+	Sort   string         `yaml:"sort"`   //queue: depth or growth
+	Key    string         `yaml:"key"`    //queue: switches the sort
+	Series []seriesConfig `yaml:"series"` //plot lines, bars
+	Text   string         `yaml:"text"`   //text: a Go template, see dashboardFrame
+	node   exprNode
+	hide   *regexp.Regexp
+	text   *template.Template
+	exprs  map[string]exprNode
+}
+
+//seriesConfig a line of a plot or a bar, one per series the expression returns
+type seriesConfig struct {
+	Expr  string `yaml:"expr"`
+	Label string `yaml:"label"`
+	Color string `yaml:"color"`
+	node  exprNode
+}
+
+var layoutColors = map[string]ui.Color{
+	"black":   ui.ColorBlack,
+	"red":     ui.ColorRed,
+	"green":   ui.ColorGreen,
+	"yellow":  ui.ColorYellow,
+	"blue":    ui.ColorBlue,
+	"magenta": ui.ColorMagenta,
+	"cyan":    ui.ColorCyan,
+	"white":   ui.ColorWhite,
+}
+
+//layoutPalette colors of series without one
+var layoutPalette = []ui.Color{ui.ColorGreen, ui.ColorBlue, ui.ColorRed, ui.ColorYellow, ui.ColorMagenta, ui.ColorCyan, ui.ColorWhite}
+
+//parseColor an empty name is the fallback
+func parseColor(name string, fallback ui.Color) (ui.Color, error) {
+	if name == "" {
+		return fallback, nil
+	}
+	color, ok := layoutColors[strings.ToLower(name)]
+	if !ok {
+		return fallback, errors.New("Invalid color:" + name + ", expected black, red, green, yellow, blue, magenta, cyan or white")
+	}
+	return color, nil
+}
+
+//loadLayout read a layout file, the built-in layout without a path
+func loadLayout(path string) (*layoutNode, error) {
+	if path == "" {
+		return parseLayout(defaultLayout)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseLayout(data)
+}
+
+//parseLayout check the whole grid and compile every expression and template up front
+func parseLayout(data []byte) (*layoutNode, error) {
+	var root layoutNode
+	if err := yaml.UnmarshalStrict(data, &root); err != nil {
+		return nil, errors.New("Invalid layout file:" + err.Error())
+	}
+	if err := root.validate("layout", make(map[string]bool)); err != nil {
+		return nil, err
+	}
+	return &root, nil
+}
+
+//validate keys are the tab keys taken so far
+func (n *layoutNode) validate(path string, keys map[string]bool) error {
+	set := 0
+	for _, ok := range []bool{len(n.Rows) > 0, len(n.Columns) > 0, n.Panel != nil, len(n.Tabs) > 0} {
+		if ok {
+			set++
+		}
+	}
+	if set != 1 {
+		return errors.New(path + ": expected exactly one of rows, columns, panel or tabs")
+	}
+	if n.Key != "" && len(n.Tabs) == 0 {
+		return errors.New(path + ": only tabs have a key")
+	}
+	if n.Panel != nil {
+		return n.Panel.validate(path, keys)
+	}
+	if len(n.Tabs) > 0 {
+		return n.validateTabs(path, keys)
+	}
+	children, kind := n.Rows, "rows"
+	if len(n.Columns) > 0 {
+		children, kind = n.Columns, "columns"
+	}
+	if err := splitRatios(children); err != nil {
+		return errors.New(path + "." + kind + ": " + err.Error())
+	}
+	for i := range children {
+		if err := children[i].validate(path+"."+kind+"["+strconv.Itoa(i)+"]", keys); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//validateTabs every tab is a panel, the title tells which key switches to the next one
+func (n *layoutNode) validateTabs(path string, keys map[string]bool) error {
+	if len(n.Tabs) < 2 {
+		return errors.New(path + ": tabs need at least two panels")
+	}
+	if n.Key == "" {
+		return errors.New(path + ": tabs need a key")
+	}
+	if err := claimKey(n.Key, keys); err != nil {
+		return errors.New(path + ": " + err.Error())
+	}
+	for i := range n.Tabs {
+		if err := n.Tabs[i].validate(path+".tabs["+strconv.Itoa(i)+"]", keys); err != nil {
+			return err
+		}
+		n.Tabs[i].Title += " (" + n.Key + ": next)"
+	}
+	return nil
+}
+
+//claimKey keys are single characters, each bound once across the layout
+func claimKey(key string, keys map[string]bool) error {
+	switch {
+	case len([]rune(key)) != 1:
+		return errors.New("Invalid key:" + key + ", expected a single character")
+	case key == "q":
+		return errors.New("q is taken by quit")
+	case keys[key]:
+		return errors.New("key " + key + " is already used")
+	}
+	keys[key] = true
+	return nil
+}
+
+//splitRatios give siblings without a ratio an equal share of what is left
+func splitRatios(nodes []layoutNode) error {
+	var total float64
+	var unset int
+	for _, node := range nodes {
+		if node.Ratio < 0 {
+			return errors.New("Invalid ratio value:" + strconv.FormatFloat(node.Ratio, 'f', -1, 64))
+		}
+		if node.Ratio == 0 {
+			unset++
+		}
+		total += node.Ratio
+	}
+	//leave room for rounding, 0.33 three times is fine
+	if total > 1.01 || (unset > 0 && total >= 1) {
+		return errors.New("ratios add up to " + strconv.FormatFloat(total, 'f', -1, 64) + ", more than 1")
+	}
+	for i := range nodes {
+		if nodes[i].Ratio == 0 {
+			nodes[i].Ratio = (1 - total) / float64(unset)
+		}
+	}
+	return nil
+}
+
+//validate keys are the keys taken so far
+func (p *panelConfig) validate(path string, keys map[string]bool) error {
+	if p.Title != "" {
+		path += " (" + p.Title + ")"
+	}
+	fail := func(err error) error {
+		return errors.New(path + ": " + err.Error())
+	}
+	if _, err := parseColor(p.Color, ui.ColorWhite); err != nil {
+		return fail(err)
+	}
+	if p.Type != "queue" && (p.Key != "" || p.Sort != "") {
+		return fail(errors.New("only queue panels have a key and sort"))
+	}
+	switch p.Type {
+	case "queue":
+		switch p.Sort {
+		case "", "depth", "growth":
+		default:
+			return fail(errors.New("Invalid sort value:" + p.Sort + ", expected depth or growth"))
+		}
+		if p.Key != "" {
+			if err := claimKey(p.Key, keys); err != nil {
+				return fail(err)
+			}
+		}
+		fallthrough
+	case "gauge", "list":
+		if p.Expr == "" {
+			return fail(errors.New(p.Type + " panels need an expr"))
+		}
+		node, err := parseExpr(p.Expr)
+		if err != nil {
+			return fail(err)
+		}
+		p.node = node
+		if p.Hide != "" {
+			p.hide, err = regexp.Compile(p.Hide)
+			if err != nil {
+				return fail(errors.New("Invalid hide regex " + p.Hide + ":" + err.Error()))
+			}
+		}
+	case "plot", "bar":
+		if len(p.Series) == 0 {
+			return fail(errors.New(p.Type + " panels need at least one series"))
+		}
+		for i := range p.Series {
+			node, err := parseExpr(p.Series[i].Expr)
+			if err != nil {
+				return fail(err)
+			}
+			p.Series[i].node = node
+			if _, err := parseColor(p.Series[i].Color, ui.ColorWhite); err != nil {
+				return fail(err)
+			}
+		}
+	case "text":
+		text, err := template.New(p.Title).Parse(p.Text)
+		if err != nil {
+			return fail(err)
+		}
+		//expressions inside the template are only parsed when it runs, so run it once on no data,
+		//which also parses them once for every frame after
+		p.exprs = make(map[string]exprNode)
+		if err := text.Execute(ioutil.Discard, &dashboardFrame{series: exprSeries{}, exprs: p.exprs}); err != nil {
+			return fail(err)
+		}
+		p.text = text
+	default:
+		return fail(errors.New("Invalid panel type:" + p.Type + ", expected gauge, plot, bar, list, queue or text"))
+	}
+	return nil
+}
+
+//clampPercent a gauge percentage, 0 when there is no data
+func clampPercent(value float64) int {
+	if math.IsNaN(value) || value < 0 {
+		return 0
+	}
+	if value > 100 {
+		return 100
+	}
+	return int(value)
+}
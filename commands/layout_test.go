@@ -0,0 +1,94 @@
+package commands
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gizak/termui/v3/widgets"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDefaultLayout(t *testing.T) {
+	root, err := loadLayout("")
+	assert.NoError(t, err)
+	assert.Len(t, root.Columns, 2)
+	//the plot column takes what the first one leaves
+	assert.InDelta(t, 0.47, root.Columns[1].Ratio, 0.0001)
+
+	//the same lines as the hard-coded dashboard had
+	chart := root.Columns[1].Rows[0].Panel
+	assert.Equal(t, "DB Connection Chart", chart.Title)
+	var labels []string
+	for _, series := range chart.Series {
+		labels = append(labels, series.Label)
+	}
+	assert.Equal(t, []string{"Active", "Max", "Idle", "MinIdle"}, labels)
+
+	assert.Len(t, newDashboard(root, time.Minute).panels, 12)
+}
+
+func TestParseLayoutErrors(t *testing.T) {
+	for input, expected := range map[string]string{
+		"rows:\n  - panel: {type: pie, expr: up}":                                                                                                       "Invalid panel type:pie",
+		"rows:\n  - panel: {type: gauge}":                                                                                                               "gauge panels need an expr",
+		"rows:\n  - panel: {type: gauge, expr: 'up +'}":                                                                                                 "Invalid expression",
+		"rows:\n  - panel: {type: gauge, expr: up, color: pink}":                                                                                        "Invalid color:pink",
+		"rows:\n  - panel: {type: plot, title: load}":                                                                                                   "layout.rows[0] (load): plot panels need at least one series",
+		"rows:\n  - panel: {type: text, text: '{{.Value \"up +\"}}'}":                                                                                   "Invalid expression",
+		"rows:\n  - {ratio: 0.7, panel: {type: gauge, expr: up}}\n  - ratio: 0.5\n    panel: {type: gauge, expr: up}":                                   "more than 1",
+		"rows:\n  - columns: [{panel: {type: gauge, expr: up}}]\n    panel: {type: gauge, expr: up}":                                                    "layout.rows[0]: expected exactly one of rows, columns, panel or tabs",
+		"rows:\n  - tabs: [{type: text, text: a}, {type: text, text: b}]":                                                                               "tabs need a key",
+		"rows:\n  - {key: q, tabs: [{type: text, text: a}, {type: text, text: b}]}":                                                                     "q is taken by quit",
+		"rows:\n  - {key: t, tabs: [{type: text, text: a}]}":                                                                                            "tabs need at least two panels",
+		"rows:\n  - {key: t, tabs: [{type: text, text: a}, {type: text, text: b}]}\n  - {key: t, tabs: [{type: text, text: a}, {type: text, text: b}]}": "key t is already used",
+		"rows:\n  - panel: {type: queue, expr: up, key: tt}":                                                                                            "Invalid key:tt",
+		"rows:\n  - panel: {type: queue, expr: up, sort: name}":                                                                                         "Invalid sort value:name",
+		"rows:\n  - panel: {type: list, expr: up, key: s}":                                                                                              "only queue panels have a key and sort",
+		"rows:\n  - {key: s, tabs: [{type: text, text: a}, {type: text, text: b}]}\n  - panel: {type: queue, expr: up, key: s}":                         "key s is already used",
+		"rows:\n  - {key: t, panel: {type: text, text: a}}":                                                                                             "only tabs have a key",
+		"rows:\n  - panel: {type: gauge, expr: up, size: 3}":                                                                                            "Invalid layout file",
+	} {
+		_, err := parseLayout([]byte(input))
+		if assert.Error(t, err, input) {
+			assert.Contains(t, err.Error(), expected)
+		}
+	}
+}
+
+func TestDashboardPanels(t *testing.T) {
+	root, err := parseLayout([]byte(`
+rows:
+  - panel:
+      type: gauge
+      expr: 100 - app_disk_free_bytes / app_disk_total_bytes * 100
+  - panel:
+      type: list
+      expr: queue_messages_total
+      label: queue_name
+      hide: Retry
+  - panel:
+      type: text
+      text: '{{.Count}} {{.Value "app_disk_free_bytes"}} {{printf "%.0f" (.Raw "app_disk_total_bytes")}} {{.Value "missing"}}'
+  - panel:
+      type: bar
+      series:
+        - expr: queue_messages_total
+          label: queue
+`))
+	assert.NoError(t, err)
+	panels := newDashboard(root, time.Minute).panels
+	frame := newDashboardFrame(testData())
+	for _, panel := range panels {
+		panel.update(frame)
+	}
+	assert.Equal(t, 95, panels[0].drawable().(*widgets.Gauge).Percent)
+	assert.Equal(t, []string{"Index 12000", "Persist 40"}, panels[1].drawable().(*widgets.List).Rows)
+	assert.Equal(t, "4 50 B 1000 n/a", panels[2].drawable().(*widgets.Paragraph).Text)
+	//parsed when the layout is loaded, not on every frame
+	assert.Len(t, root.Rows[2].Panel.exprs, 3)
+	assert.Equal(t, []string{`queue{queue_name="Index"}`, `queue{queue_name="IndexRetry"}`, `queue{queue_name="Persist"}`}, panels[3].drawable().(*widgets.BarChart).Labels)
+
+	frame.ShowHidden = true
+	panels[1].update(frame)
+	assert.Len(t, panels[1].drawable().(*widgets.List).Rows, 3)
+}
@@ -1,12 +1,15 @@
 package commands
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/jfrog/jfrog-cli-core/v2/plugins/components"
+	"github.com/jfrog/jfrog-cli-core/v2/utils/config"
 	"github.com/jfrog/jfrog-client-go/utils/log"
 	helpers "github.com/lorenyeung/indexcheck/utils"
 )
@@ -31,6 +34,22 @@ func getMetricsArguments() []components.Argument {
 			Name:        "list",
 			Description: "list metrics.",
 		},
+		{
+			Name:        "record",
+			Description: "record metric snapshots to a file, one JSON line per snapshot.",
+		},
+		{
+			Name:        "top",
+			Description: "live ranking of the series that change the most between polls.",
+		},
+		{
+			Name:        "query",
+			Description: "evaluate an expression, e.g. 'sum by (queue_name) (queue_messages_total)'.",
+		},
+		{
+			Name:        "check",
+			Description: "check the metrics against the rules file, exits non-zero when a rule is breached.",
+		},
 	}
 }
 
@@ -46,6 +65,42 @@ func getMetricsFlags() []components.Flag {
 			Description:  "Get minimum JSON from Xray (no whitespace)",
 			DefaultValue: false,
 		},
+		getProductFlag(),
+		components.StringFlag{
+			Name:         "format",
+			Description:  "Output format: json, table or csv. Table values are humanized where the unit is known",
+			DefaultValue: "json",
+		},
+		components.StringFlag{
+			Name:        "rules",
+			Description: "Rules file for metrics check",
+		},
+		components.StringFlag{
+			Name:        "delta",
+			Description: "Take two snapshots this far apart, e.g. 30s, and show per series deltas and per second rates, largest change first",
+		},
+		components.StringFlag{
+			Name:         "interval",
+			Description:  "Interval between snapshots for record and top",
+			DefaultValue: "10s",
+		},
+		components.StringFlag{
+			Name:         "out",
+			Description:  "File to append recorded snapshots to",
+			DefaultValue: "xray-metrics.ndjson",
+		},
+		components.StringFlag{
+			Name:        "name",
+			Description: "Only show metrics matching the name pattern, e.g. 'jfxr_db_sync_*'",
+		},
+		components.StringFlag{
+			Name:        "type",
+			Description: "Only show metrics of the type: GAUGE, COUNTER, SUMMARY, HISTOGRAM or UNTYPED",
+		},
+		components.StringFlag{
+			Name:        "label",
+			Description: "Only show metrics matching comma separated label conditions, e.g. queue_name=~Index.*. Operators: =, !=, =~, !~, regexes match the whole value",
+		},
 	}
 }
 
@@ -53,29 +108,101 @@ func getMetricsEnvVar() []components.EnvVar {
 	return []components.EnvVar{}
 }
 
+//getProductFlag shared by metrics and graph
+func getProductFlag() components.Flag {
+	return components.StringFlag{
+		Name:         "product",
+		Description:  "Metrics to get: xray, artifactory or both. both adds a product label to every metric",
+		DefaultValue: helpers.MetricsXray,
+	}
+}
+
+func getMetricsProduct(c *components.Context) (string, error) {
+	product := strings.ToLower(c.GetStringFlagValue("product"))
+	switch product {
+	case "":
+		return helpers.MetricsXray, nil
+	case helpers.MetricsXray, helpers.MetricsArtifactory, helpers.MetricsBoth:
+		return product, nil
+	}
+	return "", errors.New("Invalid product:" + product + ", expected xray, artifactory or both")
+}
+
+//getMetricsOnce metrics for a single look, where an empty response is an error instead of stale data
+func getMetricsOnce(config *config.ServerDetails, product string) ([]helpers.Data, error) {
+	data, _, _, err := helpers.GetMetricsData(config, product, 0, false, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, helpers.ErrNoMetrics
+	}
+	return data, nil
+}
+
 type MetricsConfiguration struct {
 	addressee string
 	raw       bool
 	repeat    int
 	prefix    string
 	min       bool
+	format    string
+	product   string
 }
 
 func MetricsCmd(c *components.Context) error {
 
-	config, err := helpers.GetConfig()
+	var conf = new(MetricsConfiguration)
+	//conf.addressee = c.Arguments[0]
+
+	filter, err := newMetricsFilter(c.GetStringFlagValue("name"), c.GetStringFlagValue("type"), c.GetStringFlagValue("label"))
+	if err != nil {
+		return err
+	}
+
+	conf.product, err = getMetricsProduct(c)
+	if err != nil {
+		return err
+	}
+
+	config, err := helpers.GetConfig(conf.product)
 	if err != nil {
 		return errors.New(err.Error() + " at " + string(helpers.Trace().Fn) + " on line " + string(strconv.Itoa(helpers.Trace().Line)))
 	}
 
-	var conf = new(MetricsConfiguration)
-	//conf.addressee = c.Arguments[0]
+	conf.format = strings.ToLower(c.GetStringFlagValue("format"))
+	switch conf.format {
+	case "", "json", "table", "csv":
+	default:
+		return errors.New("Invalid format:" + conf.format + ", expected json, table or csv")
+	}
 
 	if len(c.Arguments) == 0 {
 		conf.raw = c.GetBoolFlagValue("raw")
+		conf.min = c.GetBoolFlagValue("min")
+
+		if delta := c.GetStringFlagValue("delta"); delta != "" {
+			interval, err := time.ParseDuration(delta)
+			if err != nil || interval <= 0 {
+				return errors.New("Invalid delta value:" + delta)
+			}
+			if conf.raw {
+				return errors.New("--delta can not be used with --raw")
+			}
+			return metricsDelta(config, filter, interval, conf, os.Stdout)
+		}
 
 		if conf.raw {
-			metricsRaw, err := helpers.GetMetricsDataRaw(config)
+			if !filter.empty() {
+				return errors.New("--name, --type and --label can not be used with --raw")
+			}
+			if conf.format == "table" || conf.format == "csv" {
+				return errors.New("--format can not be used with --raw")
+			}
+			if conf.product == helpers.MetricsBoth {
+				return errors.New("--raw needs a single --product, xray or artifactory")
+			}
+			metricsRaw, err := helpers.GetMetricsDataRaw(config, conf.product)
 			if err != nil {
 				log.Warn(err)
 			}
@@ -86,20 +213,19 @@ func MetricsCmd(c *components.Context) error {
 			return nil
 		}
 
-		conf.min = c.GetBoolFlagValue("min")
-
-		if conf.min {
-			//return json as is, no white space
-			data, err := helpers.GetMetricsDataJSON(config, false)
-			if err != nil {
-				return errors.New(err.Error() + " at " + string(helpers.Trace().Fn) + " on line " + string(strconv.Itoa(helpers.Trace().Line)))
-			}
-			fmt.Println(string(data))
-			return nil
+		families, err := helpers.GetMetricsFamilies(config, conf.product)
+		if err != nil {
+			return errors.New(err.Error() + " at " + string(helpers.Trace().Fn) + " on line " + string(strconv.Itoa(helpers.Trace().Line)))
 		}
-
-		//else pretty print json
-		data, err := helpers.GetMetricsDataJSON(config, true)
+		families = filter.apply(families)
+		switch conf.format {
+		case "table":
+			return writeMetricsTable(os.Stdout, flattenFamilies(families))
+		case "csv":
+			return writeMetricsCSV(os.Stdout, flattenFamilies(families))
+		}
+		//return json as is with --min, no white space, else pretty print json
+		data, err := helpers.MetricsFamiliesJSON(families, !conf.min)
 		if err != nil {
 			return errors.New(err.Error() + " at " + string(helpers.Trace().Fn) + " on line " + string(strconv.Itoa(helpers.Trace().Line)))
 		}
@@ -111,18 +237,56 @@ func MetricsCmd(c *components.Context) error {
 		var err error
 		switch arg := c.Arguments[0]; arg {
 		case "list":
-			jsonText, err := helpers.GetMetricsDataJSON(config, false)
+			families, err := helpers.GetMetricsFamilies(config, conf.product)
 			if err != nil {
 				return errors.New(err.Error() + " at " + string(helpers.Trace().Fn) + " on line " + string(strconv.Itoa(helpers.Trace().Line)))
 			}
-			var metricsData []helpers.Data
-			err = json.Unmarshal(jsonText, &metricsData)
+			families = filter.apply(families)
+			fmt.Println("Found", len(families), "metrics")
+			for i := range families {
+				labels := familyLabelNames(families[i])
+				if len(labels) == 0 {
+					fmt.Println(families[i].Name, families[i].Type)
+					continue
+				}
+				fmt.Println(families[i].Name, families[i].Type, "{"+strings.Join(labels, ",")+"}")
+			}
+			return nil
+		case "record":
+			interval, err := time.ParseDuration(c.GetStringFlagValue("interval"))
+			if err != nil || interval <= 0 {
+				return errors.New("Invalid interval value:" + c.GetStringFlagValue("interval"))
+			}
+			return recordMetrics(config, conf.product, filter, interval, c.GetStringFlagValue("out"))
+		case "top":
+			interval, err := time.ParseDuration(c.GetStringFlagValue("interval"))
+			if err != nil || interval <= 0 {
+				return errors.New("Invalid interval value:" + c.GetStringFlagValue("interval"))
+			}
+			return metricsTop(config, conf, filter, interval)
+		case "query":
+			return errors.New("Please provide an expression to query, e.g. 'sum by (queue_name) (queue_messages_total)'")
+		case "check":
+			rules, err := loadMetricsRules(c.GetStringFlagValue("rules"))
 			if err != nil {
-				return errors.New(err.Error() + " at " + string(helpers.Trace().Fn) + " on line " + string(strconv.Itoa(helpers.Trace().Line)))
+				return err
+			}
+			data, err := getMetricsOnce(config, conf.product)
+			if err != nil {
+				return err
 			}
-			fmt.Println("Found", len(metricsData), "metrics")
-			for i := range metricsData {
-				fmt.Println(metricsData[i].Name)
+			results, err := evalRules(rules, data)
+			if err != nil {
+				return err
+			}
+			breached, noData := printRuleResults(os.Stdout, results)
+			switch {
+			case breached > 0 && noData > 0:
+				return errors.New(strconv.Itoa(breached) + " of " + strconv.Itoa(len(rules)) + " rules breached, " + strconv.Itoa(noData) + " without data")
+			case breached > 0:
+				return errors.New(strconv.Itoa(breached) + " of " + strconv.Itoa(len(rules)) + " rules breached")
+			case noData > 0:
+				return errors.New(strconv.Itoa(noData) + " of " + strconv.Itoa(len(rules)) + " rules without data")
 			}
 			return nil
 		default:
@@ -131,6 +295,21 @@ func MetricsCmd(c *components.Context) error {
 
 		return errors.New(err.Error() + " at " + string(helpers.Trace().Fn) + " on line " + string(strconv.Itoa(helpers.Trace().Line)))
 	}
-	return errors.New("Wrong number of arguments. Expected: 0 or 1, " + "Received: " + strconv.Itoa(len(c.Arguments)))
+	if len(c.Arguments) == 2 && c.Arguments[0] == "query" {
+		node, err := parseExpr(c.Arguments[1])
+		if err != nil {
+			return err
+		}
+		data, err := getMetricsOnce(config, conf.product)
+		if err != nil {
+			return err
+		}
+		value, err := evalExpr(node, data)
+		if err != nil {
+			return err
+		}
+		return writeQueryResult(os.Stdout, value, conf)
+	}
+	return errors.New("Wrong number of arguments. Expected: 0, 1 or query with an expression, " + "Received: " + strconv.Itoa(len(c.Arguments)))
 
 }
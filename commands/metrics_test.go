@@ -0,0 +1,30 @@
+package commands
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jfrog/jfrog-cli-core/v2/utils/config"
+	helpers "github.com/lorenyeung/indexcheck/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetMetricsOnce(t *testing.T) {
+	body := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+	serverConfig := &config.ServerDetails{Url: server.URL + "/"}
+
+	//an empty response is not an empty result
+	_, err := getMetricsOnce(serverConfig, helpers.MetricsXray)
+	assert.True(t, errors.Is(err, helpers.ErrNoMetrics))
+
+	body = "# TYPE sys_load_1 gauge\nsys_load_1 1.5\n"
+	data, err := getMetricsOnce(serverConfig, helpers.MetricsXray)
+	assert.NoError(t, err)
+	assert.Len(t, data, 1)
+}
@@ -0,0 +1,143 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+
+	helpers "github.com/lorenyeung/indexcheck/utils"
+	"gopkg.in/yaml.v2"
+)
+
+//metricsRules rules file of metrics check
+type metricsRules struct {
+	Rules []metricsRule `yaml:"rules"`
+}
+
+//metricsRule breached when expr returns any series, e.g. queue_messages_total{queue_name="Index"} > 10000
+type metricsRule struct {
+	Name        string `yaml:"name"`
+	Expr        string `yaml:"expr"`
+	Description string `yaml:"description"`
+	node        exprNode
+}
+
+//ruleResult series of a rule that breached
+type ruleResult struct {
+	Rule     metricsRule
+	Breaches []exprSample
+	NoData   bool
+}
+
+//loadMetricsRules read and parse every rule up front, so a typo fails before anything is fetched
+func loadMetricsRules(path string) ([]metricsRule, error) {
+	if path == "" {
+		return nil, errors.New("Please provide a rules file with --rules")
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseMetricsRules(data)
+}
+
+func parseMetricsRules(data []byte) ([]metricsRule, error) {
+	var rules metricsRules
+	err := yaml.UnmarshalStrict(data, &rules)
+	if err != nil {
+		return nil, errors.New("Invalid rules file:" + err.Error())
+	}
+	if len(rules.Rules) == 0 {
+		return nil, errors.New("No rules found in rules file")
+	}
+	for i := range rules.Rules {
+		if rules.Rules[i].Name == "" {
+			rules.Rules[i].Name = "rule " + strconv.Itoa(i+1)
+		}
+		rules.Rules[i].node, err = parseExpr(rules.Rules[i].Expr)
+		if err != nil {
+			return nil, errors.New(rules.Rules[i].Name + ": " + err.Error())
+		}
+	}
+	return rules.Rules, nil
+}
+
+//evalRules a rule with a comparison breaches with the series that matched it,
+//a rule without one breaches when its value is not 0
+func evalRules(rules []metricsRule, data []helpers.Data) ([]ruleResult, error) {
+	series := newExprSeries(data)
+	var results []ruleResult
+	for _, rule := range rules {
+		value, err := rule.node.eval(series)
+		if err != nil {
+			return nil, errors.New(rule.Name + ": " + err.Error())
+		}
+		result := ruleResult{Rule: rule}
+		switch {
+		case value.Scalar:
+			if value.Samples[0].Value != 0 {
+				result.Breaches = value.Samples
+			}
+		case len(value.Samples) > 0:
+			result.Breaches = value.Samples
+		default:
+			//comparisons filter, so an empty result only says the rule holds when its metrics are there
+			result.NoData, err = missingSelector(rule.node, series)
+			if err != nil {
+				return nil, errors.New(rule.Name + ": " + err.Error())
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+//missingSelector true when any selector of the expression matches no series, e.g. a misspelled metric
+func missingSelector(node exprNode, series exprSeries) (bool, error) {
+	switch n := node.(type) {
+	case selectorNode:
+		value, err := n.eval(series)
+		return len(value.Samples) == 0, err
+	case binaryNode:
+		missing, err := missingSelector(n.LHS, series)
+		if missing || err != nil {
+			return missing, err
+		}
+		return missingSelector(n.RHS, series)
+	case negNode:
+		return missingSelector(n.Expr, series)
+	case aggregateNode:
+		return missingSelector(n.Expr, series)
+	}
+	return false, nil
+}
+
+//printRuleResults returns the number of breached rules and of rules without data
+func printRuleResults(out io.Writer, results []ruleResult) (int, int) {
+	var breached, noData int
+	for _, result := range results {
+		switch {
+		case len(result.Breaches) > 0:
+			breached++
+			fmt.Fprintln(out, fmt.Sprintf("%-7v", "BREACH"), result.Rule.Name, "\t", result.Rule.Expr)
+			if result.Rule.Description != "" {
+				fmt.Fprintln(out, "\t", result.Rule.Description)
+			}
+			for _, sample := range result.Breaches {
+				name := sample.String()
+				if name == "" {
+					name = "value"
+				}
+				fmt.Fprintln(out, "\t", name, "=", humanizeValue(sample.Name, strconv.FormatFloat(sample.Value, 'f', -1, 64)))
+			}
+		case result.NoData:
+			noData++
+			fmt.Fprintln(out, fmt.Sprintf("%-7v", "NODATA"), result.Rule.Name, "\t", result.Rule.Expr)
+		default:
+			fmt.Fprintln(out, fmt.Sprintf("%-7v", "OK"), result.Rule.Name)
+		}
+	}
+	return breached, noData
+}
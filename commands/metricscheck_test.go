@@ -0,0 +1,48 @@
+package commands
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricsRules(t *testing.T) {
+	rules, err := parseMetricsRules([]byte(`rules:
+  - name: index-backlog
+    expr: queue_messages_total{queue_name="Index"} > 10000
+    description: Index queue is backing up
+  - name: low-disk
+    expr: app_disk_free_bytes / app_disk_total_bytes < 0.01
+  - name: sync-stuck
+    expr: jfxr_db_sync_started_before_seconds
+  - name: typo
+    expr: queue_mesages_total{queue_name="Index"} > 10000
+  - name: missing-label
+    expr: queue_messages_total{queue_name=~"Index"} / app_disk_total_bytes{mount="/data"} > 10
+`))
+	assert.NoError(t, err)
+	results, err := evalRules(rules, testData())
+	assert.NoError(t, err)
+
+	var out bytes.Buffer
+	breached, noData := printRuleResults(&out, results)
+	assert.Equal(t, 1, breached)
+	assert.Equal(t, 3, noData)
+	assert.Contains(t, out.String(), "BREACH  index-backlog")
+	assert.Contains(t, out.String(), `queue_messages_total{queue_name="Index"} = 12000`)
+	assert.Contains(t, out.String(), "OK      low-disk")
+	assert.Contains(t, out.String(), "NODATA  sync-stuck")
+	//comparisons with an operand matching no series are not OK
+	assert.Contains(t, out.String(), "NODATA  typo")
+	assert.Contains(t, out.String(), "NODATA  missing-label")
+}
+
+func TestMetricsRulesInvalid(t *testing.T) {
+	_, err := parseMetricsRules([]byte("rules: []\n"))
+	assert.Error(t, err)
+	_, err = parseMetricsRules([]byte("rules:\n  - name: typo\n    expr: queue_messages_total >\n"))
+	assert.EqualError(t, err, "typo: Invalid expression queue_messages_total >: expected a metric, number or ( at end of expression")
+	_, err = parseMetricsRules([]byte("rules:\n  - name: typo\n    exp: queue_messages_total\n"))
+	assert.Error(t, err)
+}
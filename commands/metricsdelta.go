@@ -0,0 +1,133 @@
+package commands
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jfrog/jfrog-cli-core/v2/utils/config"
+	helpers "github.com/lorenyeung/indexcheck/utils"
+)
+
+//metricDelta change of a single series between two snapshots
+type metricDelta struct {
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Before float64           `json:"before"`
+	After  float64           `json:"after"`
+	Delta  float64           `json:"delta"`
+	Rate   float64           `json:"rate"` //per second
+	Reset  bool              `json:"reset,omitempty"`
+}
+
+//diffSnapshots deltas of the series found in both snapshots, largest change first.
+//A counter that went down was reset, its delta is counted from zero
+func diffSnapshots(before, after []metricRow, elapsed time.Duration) []metricDelta {
+	beforeMap := make(map[string]float64, len(before))
+	for _, row := range before {
+		value, err := strconv.ParseFloat(row.Value, 64)
+		if err == nil {
+			beforeMap[row.Name+"{"+formatLabels(row.Labels)+"}"] = value
+		}
+	}
+	deltas := []metricDelta{}
+	for _, row := range after {
+		value, err := strconv.ParseFloat(row.Value, 64)
+		if err != nil {
+			continue
+		}
+		previous, ok := beforeMap[row.Name+"{"+formatLabels(row.Labels)+"}"]
+		if !ok {
+			continue
+		}
+		delta := metricDelta{Name: row.Name, Labels: row.Labels, Before: previous, After: value, Delta: value - previous}
+		if row.Counter && value < previous {
+			delta.Reset = true
+			delta.Delta = value
+		}
+		if math.IsNaN(delta.Delta) || math.IsInf(delta.Delta, 0) {
+			continue
+		}
+		if elapsed > 0 {
+			delta.Rate = delta.Delta / elapsed.Seconds()
+		}
+		deltas = append(deltas, delta)
+	}
+	sort.SliceStable(deltas, func(i, j int) bool {
+		a, b := math.Abs(deltas[i].Delta), math.Abs(deltas[j].Delta)
+		if a != b {
+			return a > b
+		}
+		return deltas[i].Name+formatLabels(deltas[i].Labels) < deltas[j].Name+formatLabels(deltas[j].Labels)
+	})
+	return deltas
+}
+
+//metricsDelta take two snapshots interval apart and print what changed
+func metricsDelta(config *config.ServerDetails, filter *metricsFilter, interval time.Duration, conf *MetricsConfiguration, out io.Writer) error {
+	first, err := helpers.GetMetricsFamilies(config, conf.product)
+	if err != nil {
+		return err
+	}
+	firstTime := time.Now()
+	time.Sleep(interval)
+	second, err := helpers.GetMetricsFamilies(config, conf.product)
+	if err != nil {
+		return err
+	}
+	deltas := diffSnapshots(flattenFamilies(filter.apply(first)), flattenFamilies(filter.apply(second)), time.Since(firstTime))
+
+	switch conf.format {
+	case "table":
+		return writeDeltaTable(out, deltas)
+	case "csv":
+		return writeDeltaCSV(out, deltas)
+	}
+	var data []byte
+	if conf.min {
+		data, err = json.Marshal(deltas)
+	} else {
+		data, err = json.MarshalIndent(deltas, "", "    ")
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(out, string(data))
+	return nil
+}
+
+func writeDeltaTable(out io.Writer, deltas []metricDelta) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tLABELS\tBEFORE\tAFTER\tDELTA\tRATE")
+	for _, d := range deltas {
+		rate := fmt.Sprintf("%.2f/s", d.Rate)
+		if strings.HasSuffix(strings.TrimSuffix(d.Name, "_total"), "_bytes") {
+			rate = humanizeValue(d.Name, strconv.FormatFloat(d.Rate, 'f', -1, 64)) + "/s"
+		}
+		delta := humanizeValue(d.Name, strconv.FormatFloat(d.Delta, 'f', -1, 64))
+		if d.Reset {
+			delta += " (reset)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", d.Name, formatLabels(d.Labels),
+			humanizeValue(d.Name, strconv.FormatFloat(d.Before, 'f', -1, 64)), humanizeValue(d.Name, strconv.FormatFloat(d.After, 'f', -1, 64)), delta, rate)
+	}
+	return w.Flush()
+}
+
+func writeDeltaCSV(out io.Writer, deltas []metricDelta) error {
+	w := csv.NewWriter(out)
+	w.Write([]string{"name", "labels", "before", "after", "delta", "rate", "reset"})
+	for _, d := range deltas {
+		w.Write([]string{d.Name, formatLabels(d.Labels), strconv.FormatFloat(d.Before, 'f', -1, 64), strconv.FormatFloat(d.After, 'f', -1, 64),
+			strconv.FormatFloat(d.Delta, 'f', -1, 64), strconv.FormatFloat(d.Rate, 'f', -1, 64), strconv.FormatBool(d.Reset)})
+	}
+	w.Flush()
+	return w.Error()
+}
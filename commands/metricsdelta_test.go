@@ -0,0 +1,39 @@
+package commands
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	before := []metricRow{
+		{Name: "jfxr_data_artifacts_total", Labels: map[string]string{"package_type": "npm"}, Value: "100", Counter: true},
+		{Name: "jfxr_data_artifacts_total", Labels: map[string]string{"package_type": "docker"}, Value: "500", Counter: true},
+		{Name: "queue_messages_total", Labels: map[string]string{"queue_name": "Index"}, Value: "40"},
+		{Name: "removed_metric", Value: "1"},
+	}
+	after := []metricRow{
+		{Name: "jfxr_data_artifacts_total", Labels: map[string]string{"package_type": "npm"}, Value: "130", Counter: true},
+		{Name: "jfxr_data_artifacts_total", Labels: map[string]string{"package_type": "docker"}, Value: "20", Counter: true},
+		{Name: "queue_messages_total", Labels: map[string]string{"queue_name": "Index"}, Value: "0"},
+		{Name: "new_metric", Value: "1"},
+	}
+	deltas := diffSnapshots(before, after, 10*time.Second)
+	assert.Len(t, deltas, 3)
+
+	//gauges may go down, sorted by absolute change
+	assert.Equal(t, "queue_messages_total", deltas[0].Name)
+	assert.Equal(t, float64(-40), deltas[0].Delta)
+	assert.Equal(t, float64(-4), deltas[0].Rate)
+
+	assert.Equal(t, "npm", deltas[1].Labels["package_type"])
+	assert.Equal(t, float64(30), deltas[1].Delta)
+	assert.Equal(t, float64(3), deltas[1].Rate)
+
+	//counter reset counts from zero
+	assert.Equal(t, "docker", deltas[2].Labels["package_type"])
+	assert.True(t, deltas[2].Reset)
+	assert.Equal(t, float64(20), deltas[2].Delta)
+}
@@ -0,0 +1,194 @@
+package commands
+
+import (
+	"errors"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/prometheus/prom2json"
+)
+
+//labelMatcher a single label condition, same operators as a Prometheus selector
+type labelMatcher struct {
+	Name  string
+	Op    string //=, !=, =~ or !~
+	Value string
+	re    *regexp.Regexp
+}
+
+//parseLabelMatcher parse name=value, name!=value, name=~regex or name!~regex, the value may be quoted
+func parseLabelMatcher(s string) (labelMatcher, error) {
+	//the operator is the first one after the name, the value may hold any of them
+	i := strings.IndexAny(s, "=!")
+	if i > 0 {
+		op := "="
+		for _, o := range []string{"!~", "=~", "!="} {
+			if strings.HasPrefix(s[i:], o) {
+				op = o
+			}
+		}
+		if op != "=" || s[i] == '=' {
+			value := strings.TrimSpace(s[i+len(op):])
+			if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+				value = value[1 : len(value)-1]
+			}
+			return newLabelMatcher(strings.TrimSpace(s[:i]), op, value)
+		}
+	}
+	return labelMatcher{}, errors.New("Invalid label filter:" + s + ", expected name=value, name!=value, name=~regex or name!~regex")
+}
+
+func newLabelMatcher(name, op, value string) (labelMatcher, error) {
+	matcher := labelMatcher{Name: name, Op: op, Value: value}
+	if op == "=~" || op == "!~" {
+		//anchored like Prometheus, the whole value has to match
+		re, err := regexp.Compile("^(?:" + value + ")$")
+		if err != nil {
+			return labelMatcher{}, errors.New("Invalid label regex " + value + ":" + err.Error())
+		}
+		matcher.re = re
+	}
+	return matcher, nil
+}
+
+//matches a missing label is treated as an empty value
+func (m labelMatcher) matches(labels map[string]string) bool {
+	value := labels[m.Name]
+	switch m.Op {
+	case "=":
+		return value == m.Value
+	case "!=":
+		return value != m.Value
+	case "=~":
+		return m.re.MatchString(value)
+	default:
+		return !m.re.MatchString(value)
+	}
+}
+
+//metricsFilter narrow down metric families, empty fields match everything
+type metricsFilter struct {
+	Name   string //glob, e.g. jfxr_db_sync_*
+	Type   string //GAUGE, COUNTER, SUMMARY, HISTOGRAM or UNTYPED
+	Labels []labelMatcher
+}
+
+//splitLabelList split label conditions on the commas between them, commas inside quotes or brackets
+//belong to the value, e.g. queue_name=~"a{1,3}"
+func splitLabelList(labels string) []string {
+	var conditions []string
+	var quote rune
+	depth, start := 0, 0
+	add := func(condition string) {
+		condition = strings.TrimSpace(condition)
+		if condition != "" {
+			conditions = append(conditions, condition)
+		}
+	}
+	for i, c := range labels {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(' || c == '[' || c == '{':
+			depth++
+		case c == ')' || c == ']' || c == '}':
+			depth--
+		case c == ',' && depth <= 0:
+			add(labels[start:i])
+			start = i + 1
+		}
+	}
+	add(labels[start:])
+	return conditions
+}
+
+//newMetricsFilter labels is a comma separated list of label conditions
+func newMetricsFilter(name, metricType, labels string) (*metricsFilter, error) {
+	filter := &metricsFilter{Name: name, Type: strings.ToUpper(metricType)}
+	if name != "" {
+		if _, err := path.Match(name, ""); err != nil {
+			return nil, errors.New("Invalid name pattern:" + name)
+		}
+	}
+	switch filter.Type {
+	case "", "GAUGE", "COUNTER", "SUMMARY", "HISTOGRAM", "UNTYPED":
+	default:
+		return nil, errors.New("Invalid metric type:" + metricType + ", expected GAUGE, COUNTER, SUMMARY, HISTOGRAM or UNTYPED")
+	}
+	for _, label := range splitLabelList(labels) {
+		matcher, err := parseLabelMatcher(label)
+		if err != nil {
+			return nil, err
+		}
+		filter.Labels = append(filter.Labels, matcher)
+	}
+	return filter, nil
+}
+
+func (f *metricsFilter) empty() bool {
+	return f.Name == "" && f.Type == "" && len(f.Labels) == 0
+}
+
+//apply keep matching families, and within them only matching metrics. Families left without metrics are dropped
+func (f *metricsFilter) apply(families []*prom2json.Family) []*prom2json.Family {
+	if f.empty() {
+		return families
+	}
+	result := []*prom2json.Family{}
+	for _, family := range families {
+		if !f.matchesFamily(family.Name, family.Type) {
+			continue
+		}
+		if len(f.Labels) == 0 {
+			result = append(result, family)
+			continue
+		}
+		filtered := *family
+		filtered.Metrics = nil
+		for _, metric := range family.Metrics {
+			if f.matchesLabels(metricLabels(metric)) {
+				filtered.Metrics = append(filtered.Metrics, metric)
+			}
+		}
+		if len(filtered.Metrics) > 0 {
+			result = append(result, &filtered)
+		}
+	}
+	return result
+}
+
+func (f *metricsFilter) matchesFamily(name, metricType string) bool {
+	if f.Name != "" {
+		if ok, _ := path.Match(f.Name, name); !ok {
+			return false
+		}
+	}
+	return f.Type == "" || metricType == f.Type
+}
+
+func (f *metricsFilter) matchesLabels(labels map[string]string) bool {
+	for _, matcher := range f.Labels {
+		if !matcher.matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+//metricLabels labels of any of the prom2json metric types
+func metricLabels(metric interface{}) map[string]string {
+	switch m := metric.(type) {
+	case prom2json.Metric:
+		return m.Labels
+	case prom2json.Summary:
+		return m.Labels
+	case prom2json.Histogram:
+		return m.Labels
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/prometheus/prom2json"
+	"github.com/stretchr/testify/assert"
+)
+
+func testFamilies() []*prom2json.Family {
+	return []*prom2json.Family{
+		{Name: "jfxr_db_sync_started_before_secs", Type: "GAUGE", Metrics: []interface{}{
+			prom2json.Metric{Labels: map[string]string{}, Value: "120"},
+		}},
+		{Name: "queue_messages_total", Type: "GAUGE", Metrics: []interface{}{
+			prom2json.Metric{Labels: map[string]string{"queue_name": "Index"}, Value: "5"},
+			prom2json.Metric{Labels: map[string]string{"queue_name": "Persist"}, Value: "2"},
+			prom2json.Metric{Labels: map[string]string{"queue_name": "IndexRetry"}, Value: "1"},
+		}},
+		{Name: "jfxr_data_artifacts_total", Type: "COUNTER", Metrics: []interface{}{
+			prom2json.Metric{Labels: map[string]string{"package_type": "npm"}, Value: "42"},
+		}},
+	}
+}
+
+func TestMetricsFilter(t *testing.T) {
+	filter, err := newMetricsFilter("jfxr_*", "", "")
+	assert.NoError(t, err)
+	families := filter.apply(testFamilies())
+	assert.Len(t, families, 2)
+
+	filter, err = newMetricsFilter("", "gauge", "queue_name=~Index.*")
+	assert.NoError(t, err)
+	families = filter.apply(testFamilies())
+	assert.Len(t, families, 1)
+	assert.Equal(t, "queue_messages_total", families[0].Name)
+	assert.Len(t, families[0].Metrics, 2)
+
+	filter, err = newMetricsFilter("", "", "queue_name!=Index, queue_name!~.*Retry")
+	assert.NoError(t, err)
+	families = filter.apply(testFamilies())
+	//metrics without the label have an empty value, so they match !=
+	assert.Len(t, families, 3)
+	assert.Len(t, families[1].Metrics, 1)
+
+	//regexes match the whole value
+	filter, err = newMetricsFilter("", "", "queue_name=~Index")
+	assert.NoError(t, err)
+	assert.Len(t, filter.apply(testFamilies())[0].Metrics, 1)
+
+	//commas and operators inside a quoted or bracketed value belong to it
+	filter, err = newMetricsFilter("", "", `queue_name=~"^(Index|Persist),?$", queue_name!="a!=b", queue_name=~^[A-Z]{1,10}$`)
+	assert.NoError(t, err)
+	assert.Len(t, filter.Labels, 3)
+	assert.Equal(t, "^(Index|Persist),?$", filter.Labels[0].Value)
+	assert.Equal(t, labelMatcher{Name: "queue_name", Op: "!=", Value: "a!=b"}, filter.Labels[1])
+	assert.Equal(t, "^[A-Z]{1,10}$", filter.Labels[2].Value)
+
+	//filtering does not modify the source families
+	source := testFamilies()
+	filter.apply(source)
+	assert.Len(t, source[1].Metrics, 3)
+}
+
+func TestMetricsFilterInvalid(t *testing.T) {
+	_, err := newMetricsFilter("", "TIMER", "")
+	assert.Error(t, err)
+	_, err = newMetricsFilter("", "", "queue_name")
+	assert.Error(t, err)
+	_, err = newMetricsFilter("", "", "queue_name!")
+	assert.Error(t, err)
+	_, err = newMetricsFilter("", "", "queue_name=~(")
+	assert.Error(t, err)
+	_, err = newMetricsFilter("[", "", "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,153 @@
+package commands
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	helpers "github.com/lorenyeung/indexcheck/utils"
+	"github.com/prometheus/prom2json"
+)
+
+//metricRow one sample of a family, summaries and histograms are split into quantile/bucket, _sum and _count rows
+type metricRow struct {
+	Name        string
+	Labels      map[string]string
+	Value       string
+	TimestampMs string
+	Counter     bool //only goes up until Xray restarts
+}
+
+//flattenFamilies rows in the same shape as the Prometheus text format
+func flattenFamilies(families []*prom2json.Family) []metricRow {
+	var rows []metricRow
+	for _, family := range families {
+		for _, metric := range family.Metrics {
+			switch m := metric.(type) {
+			case prom2json.Metric:
+				rows = append(rows, metricRow{Name: family.Name, Labels: m.Labels, Value: m.Value, TimestampMs: m.TimestampMs, Counter: family.Type == "COUNTER"})
+			case prom2json.Summary:
+				for _, quantile := range sortedBounds(m.Quantiles) {
+					rows = append(rows, metricRow{Name: family.Name, Labels: withLabel(m.Labels, "quantile", quantile), Value: m.Quantiles[quantile], TimestampMs: m.TimestampMs})
+				}
+				rows = append(rows, metricRow{Name: family.Name + "_sum", Labels: m.Labels, Value: m.Sum, TimestampMs: m.TimestampMs, Counter: true})
+				rows = append(rows, metricRow{Name: family.Name + "_count", Labels: m.Labels, Value: m.Count, TimestampMs: m.TimestampMs, Counter: true})
+			case prom2json.Histogram:
+				for _, bound := range sortedBounds(m.Buckets) {
+					rows = append(rows, metricRow{Name: family.Name + "_bucket", Labels: withLabel(m.Labels, "le", bound), Value: m.Buckets[bound], TimestampMs: m.TimestampMs, Counter: true})
+				}
+				rows = append(rows, metricRow{Name: family.Name + "_sum", Labels: m.Labels, Value: m.Sum, TimestampMs: m.TimestampMs, Counter: true})
+				rows = append(rows, metricRow{Name: family.Name + "_count", Labels: m.Labels, Value: m.Count, TimestampMs: m.TimestampMs, Counter: true})
+			}
+		}
+	}
+	return rows
+}
+
+//sortedBounds quantile or bucket keys in numeric order, +Inf last
+func sortedBounds(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, _ := strconv.ParseFloat(keys[i], 64)
+		b, _ := strconv.ParseFloat(keys[j], 64)
+		return a < b
+	})
+	return keys
+}
+
+func withLabel(labels map[string]string, name, value string) map[string]string {
+	result := make(map[string]string, len(labels)+1)
+	for k, v := range labels {
+		result[k] = v
+	}
+	result[name] = value
+	return result
+}
+
+//familyLabelNames every label name used by the metrics of a family, sorted
+func familyLabelNames(family *prom2json.Family) []string {
+	seen := make(map[string]bool)
+	names := []string{}
+	for _, metric := range family.Metrics {
+		for name := range metricLabels(metric) {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+//formatLabels labels sorted by name, e.g. package_type="npm",repo="x"
+func formatLabels(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = name + "=" + strconv.Quote(labels[name])
+	}
+	return strings.Join(pairs, ",")
+}
+
+//humanizeValue readable value when the unit is known from the metric name, otherwise the value as is
+func humanizeValue(name, value string) string {
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+		return value
+	}
+	unit := strings.TrimSuffix(strings.TrimSuffix(name, "_total"), "_sum")
+	switch {
+	case strings.HasSuffix(unit, "_bytes"):
+		if f < 0 {
+			return "-" + helpers.ByteCountDecimal(int64(-f))
+		}
+		return helpers.ByteCountDecimal(int64(f))
+	case strings.HasSuffix(unit, "_seconds"), strings.HasSuffix(unit, "_secs"):
+		return time.Duration(f * float64(time.Second)).Round(time.Millisecond).String()
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+//formatTimestamp millisecond timestamp in layout, empty when the metric has none
+func formatTimestamp(timestampMs, layout string) string {
+	ms, err := strconv.ParseInt(timestampMs, 10, 64)
+	if err != nil {
+		return timestampMs
+	}
+	return time.Unix(0, ms*int64(time.Millisecond)).Format(layout)
+}
+
+//writeMetricsTable aligned columns with humanized values
+func writeMetricsTable(out io.Writer, rows []metricRow) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tLABELS\tVALUE\tTIMESTAMP")
+	for _, row := range rows {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", row.Name, formatLabels(row.Labels), humanizeValue(row.Name, row.Value), formatTimestamp(row.TimestampMs, "2006.01.02 15:04:05"))
+	}
+	return w.Flush()
+}
+
+//writeMetricsCSV raw values so the output can still be summed in a spreadsheet
+func writeMetricsCSV(out io.Writer, rows []metricRow) error {
+	w := csv.NewWriter(out)
+	w.Write([]string{"name", "labels", "value", "timestamp"})
+	for _, row := range rows {
+		w.Write([]string{row.Name, formatLabels(row.Labels), row.Value, formatTimestamp(row.TimestampMs, time.RFC3339)})
+	}
+	w.Flush()
+	return w.Error()
+}
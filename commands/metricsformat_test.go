@@ -0,0 +1,55 @@
+package commands
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/prom2json"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlattenFamilies(t *testing.T) {
+	families := []*prom2json.Family{
+		{Name: "sys_memory_used_bytes", Type: "GAUGE", Metrics: []interface{}{
+			prom2json.Metric{Value: "1.9554074624e+10", TimestampMs: "1638862071581"},
+		}},
+		{Name: "request_duration_seconds", Type: "HISTOGRAM", Metrics: []interface{}{
+			prom2json.Histogram{Labels: map[string]string{"path": "/api"}, Buckets: map[string]string{"+Inf": "7", "0.5": "3", "0.1": "1"}, Sum: "2.5", Count: "7"},
+		}},
+	}
+	rows := flattenFamilies(families)
+	assert.Len(t, rows, 6)
+	assert.Equal(t, "request_duration_seconds_bucket", rows[1].Name)
+	assert.Equal(t, `le="0.1",path="/api"`, formatLabels(rows[1].Labels))
+	assert.Equal(t, `le="+Inf",path="/api"`, formatLabels(rows[3].Labels))
+	assert.Equal(t, "request_duration_seconds_count", rows[5].Name)
+
+	var out bytes.Buffer
+	assert.NoError(t, writeMetricsTable(&out, rows))
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Len(t, lines, 7)
+	assert.Contains(t, lines[1], "19.6 GB")
+	//bucket counts are not seconds
+	assert.Contains(t, lines[2], " 1 ")
+	assert.Contains(t, lines[5], "2.5s")
+
+	out.Reset()
+	assert.NoError(t, writeMetricsCSV(&out, rows))
+	assert.Contains(t, out.String(), "sys_memory_used_bytes,,1.9554074624e+10,")
+	assert.Contains(t, out.String(), `"le=""0.1"",path=""/api"""`)
+}
+
+func TestHumanizeValue(t *testing.T) {
+	assert.Equal(t, "2m0s", humanizeValue("jfxr_db_sync_started_before_secs", "120"))
+	assert.Equal(t, "1.5s", humanizeValue("process_cpu_seconds_total", "1.5"))
+	assert.Equal(t, "1.0 kB", humanizeValue("jfrt_storage_bytes", "1000"))
+	assert.Equal(t, "628", humanizeValue("jfxr_data_artifacts_total", "628"))
+	assert.Equal(t, "NaN", humanizeValue("jfrt_storage_bytes", "NaN"))
+}
+
+func TestFamilyLabelNames(t *testing.T) {
+	families := testFamilies()
+	assert.Empty(t, familyLabelNames(families[0]))
+	assert.Equal(t, []string{"queue_name"}, familyLabelNames(families[1]))
+}
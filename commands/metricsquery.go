@@ -0,0 +1,59 @@
+package commands
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+)
+
+//queryResult a series of a query result as printed by metrics query
+type queryResult struct {
+	Name   string            `json:"name,omitempty"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Value  string            `json:"value"` //a string like prom2json, so NaN and Inf survive
+}
+
+//writeQueryResult print the result in the --format of the metrics command, the table humanizes values
+func writeQueryResult(out io.Writer, value exprValue, conf *MetricsConfiguration) error {
+	switch conf.format {
+	case "table":
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "SERIES\tVALUE")
+		for _, sample := range value.Samples {
+			series := sample.String()
+			if series == "" {
+				series = "{}"
+			}
+			fmt.Fprintf(w, "%s\t%s\n", series, humanizeValue(sample.Name, strconv.FormatFloat(sample.Value, 'f', -1, 64)))
+		}
+		return w.Flush()
+	case "csv":
+		w := csv.NewWriter(out)
+		w.Write([]string{"name", "labels", "value"})
+		for _, sample := range value.Samples {
+			w.Write([]string{sample.Name, formatLabels(sample.Labels), strconv.FormatFloat(sample.Value, 'f', -1, 64)})
+		}
+		w.Flush()
+		return w.Error()
+	}
+
+	results := []queryResult{}
+	for _, sample := range value.Samples {
+		results = append(results, queryResult{Name: sample.Name, Labels: sample.Labels, Value: strconv.FormatFloat(sample.Value, 'f', -1, 64)})
+	}
+	var data []byte
+	var err error
+	if conf.min {
+		data, err = json.Marshal(results)
+	} else {
+		data, err = json.MarshalIndent(results, "", "    ")
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(out, string(data))
+	return nil
+}
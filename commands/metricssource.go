@@ -0,0 +1,181 @@
+package commands
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/jfrog/jfrog-cli-core/v2/utils/config"
+	"github.com/jfrog/jfrog-client-go/utils/log"
+	helpers "github.com/lorenyeung/indexcheck/utils"
+	"github.com/prometheus/prom2json"
+)
+
+//metricsSnapshot one line of a recording
+type metricsSnapshot struct {
+	Time     time.Time           `json:"time"`
+	Families []*prom2json.Family `json:"families"`
+}
+
+//recordedSnapshot a recording line read back in the shape the graph uses
+type recordedSnapshot struct {
+	Time     time.Time      `json:"time"`
+	Families []helpers.Data `json:"families"`
+}
+
+//metricsSource where the graph gets its metrics from, same returns as helpers.GetMetricsData
+type metricsSource interface {
+	next(counter, interval int) ([]helpers.Data, string, int, error)
+	taken() time.Time //when the data of the last next was taken, the recording time on replay
+	name() string
+}
+
+//sourceEndedError the source has nothing to show, the graph stops. Any other error is shown and polled through
+type sourceEndedError struct {
+	error
+}
+
+//liveSource poll the server
+type liveSource struct {
+	config  *config.ServerDetails
+	product string
+	polled  time.Time
+}
+
+func (s *liveSource) next(counter, interval int) ([]helpers.Data, string, int, error) {
+	s.polled = time.Now()
+	return helpers.GetMetricsData(s.config, s.product, counter, false, interval)
+}
+
+func (s *liveSource) taken() time.Time {
+	return s.polled
+}
+
+func (s *liveSource) name() string {
+	return s.config.ServerId
+}
+
+//replaySource play a recording back on a clock running speed times faster than real time
+type replaySource struct {
+	path     string
+	speed    float64
+	scanner  *bufio.Scanner
+	line     int
+	current  *recordedSnapshot
+	upcoming *recordedSnapshot
+	origin   time.Time
+	start    time.Time
+	now      func() time.Time
+}
+
+func newReplaySource(r io.Reader, path string, speed float64) *replaySource {
+	scanner := bufio.NewScanner(r)
+	//a snapshot of every metric family is a long line
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+	return &replaySource{path: path, speed: speed, scanner: scanner, now: time.Now}
+}
+
+//read the next snapshot, nil at the end of the recording
+func (s *replaySource) read() (*recordedSnapshot, error) {
+	for s.scanner.Scan() {
+		s.line++
+		if len(s.scanner.Bytes()) == 0 {
+			continue
+		}
+		var snapshot recordedSnapshot
+		err := json.Unmarshal(s.scanner.Bytes(), &snapshot)
+		if err != nil {
+			return nil, errors.New("Invalid recording " + s.path + " on line " + strconv.Itoa(s.line) + ":" + err.Error())
+		}
+		return &snapshot, nil
+	}
+	return nil, s.scanner.Err()
+}
+
+//next the latest snapshot recorded before the replay clock, the last one is kept once the recording ends
+func (s *replaySource) next(counter, interval int) ([]helpers.Data, string, int, error) {
+	var err error
+	if s.current == nil {
+		s.current, err = s.read()
+		if err != nil {
+			return nil, "", 0, err
+		}
+		if s.current == nil {
+			return nil, "", 0, sourceEndedError{errors.New("No snapshots in recording " + s.path)}
+		}
+		s.origin, s.start = s.current.Time, s.now()
+		s.upcoming, err = s.read()
+		if err != nil {
+			return nil, "", 0, err
+		}
+	}
+	clock := s.origin.Add(time.Duration(float64(s.now().Sub(s.start)) * s.speed))
+	for s.upcoming != nil && !s.upcoming.Time.After(clock) {
+		s.current = s.upcoming
+		s.upcoming, err = s.read()
+		if err != nil {
+			return nil, "", 0, err
+		}
+	}
+	lastUpdate := s.current.Time.Format("2006.01.02 15:04:05")
+	if s.upcoming == nil {
+		lastUpdate += " replay finished"
+	}
+	return s.current.Families, lastUpdate, 0, nil
+}
+
+func (s *replaySource) taken() time.Time {
+	if s.current == nil {
+		return time.Time{}
+	}
+	return s.current.Time
+}
+
+func (s *replaySource) name() string {
+	return "replay of " + s.path + " at " + strconv.FormatFloat(s.speed, 'f', -1, 64) + "x"
+}
+
+//writeSnapshot append a snapshot as a single JSON line
+func writeSnapshot(w io.Writer, now time.Time, families []*prom2json.Family) error {
+	data, err := json.Marshal(metricsSnapshot{Time: now, Families: families})
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+//recordMetrics append a snapshot to out every interval until interrupted
+func recordMetrics(config *config.ServerDetails, product string, filter *metricsFilter, interval time.Duration, out string) error {
+	file, err := os.OpenFile(out, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	fmt.Println("Recording to", out, "every", interval, "press Ctrl+C to stop")
+
+	var count int
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		families, err := helpers.GetMetricsFamilies(config, product)
+		if err != nil {
+			log.Warn("Failed to get metrics:", err)
+		} else {
+			now := time.Now()
+			families = filter.apply(families)
+			err = writeSnapshot(file, now, families)
+			if err != nil {
+				return err
+			}
+			count++
+			fmt.Println(now.Format("2006.01.02 15:04:05"), "recorded snapshot", count, "with", len(families), "metrics")
+		}
+		<-ticker.C
+	}
+}
@@ -0,0 +1,54 @@
+package commands
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/prom2json"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	var recording bytes.Buffer
+	start := time.Date(2021, 12, 7, 2, 0, 0, 0, time.UTC)
+	for i, value := range []string{"5", "3", "0"} {
+		families := []*prom2json.Family{{Name: "queue_messages_total", Type: "GAUGE", Metrics: []interface{}{
+			prom2json.Metric{Labels: map[string]string{"queue_name": "Index"}, Value: value},
+		}}}
+		assert.NoError(t, writeSnapshot(&recording, start.Add(time.Duration(i)*10*time.Second), families))
+	}
+	assert.Equal(t, 3, strings.Count(recording.String(), "\n"))
+
+	now := time.Now()
+	source := newReplaySource(&recording, "xray-metrics.ndjson", 10)
+	source.now = func() time.Time { return now }
+
+	data, lastUpdate, _, err := source.next(0, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "5", data[0].Metric[0].Value)
+	assert.Equal(t, "2021.12.07 02:00:00", lastUpdate)
+	assert.Equal(t, start, source.taken())
+
+	//one second at 10x is the next snapshot
+	now = now.Add(time.Second)
+	data, _, _, err = source.next(0, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "3", data[0].Metric[0].Value)
+
+	//the last snapshot stays once the recording ends
+	now = now.Add(time.Minute)
+	data, lastUpdate, _, err = source.next(0, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "0", data[0].Metric[0].Value)
+	assert.Contains(t, lastUpdate, "replay finished")
+	assert.Equal(t, "replay of xray-metrics.ndjson at 10x", source.name())
+}
+
+func TestReplayInvalid(t *testing.T) {
+	_, _, _, err := newReplaySource(strings.NewReader(""), "empty.ndjson", 1).next(0, 1)
+	assert.Error(t, err)
+	_, _, _, err = newReplaySource(strings.NewReader("{\"time\":\"2021-12-07T02:00:00Z\"}\nnot json\n"), "bad.ndjson", 1).next(0, 1)
+	assert.EqualError(t, err, "Invalid recording bad.ndjson on line 2:invalid character 'o' in literal null (expecting 'u')")
+}
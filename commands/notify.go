@@ -0,0 +1,173 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jfrog/jfrog-client-go/utils/log"
+)
+
+//notifySummary JSON posted to the notify url
+type notifySummary struct {
+	Event      string       `json:"event"`
+	Server     string       `json:"server"`
+	Arguments  []string     `json:"arguments"`
+	Time       string       `json:"time"`
+	Duration   string       `json:"duration"`
+	Total      int          `json:"total"`
+	NotScanned int          `json:"notScanned"`
+	Failed     int          `json:"failed"`
+	Threshold  int          `json:"threshold,omitempty"`
+	Error      string       `json:"error,omitempty"` //why the check failed, the counts are what it got through
+	Repos      []notifyRepo `json:"repos"`
+}
+
+type notifyRepo struct {
+	Name       string `json:"name"`
+	Total      int    `json:"total"`
+	NotScanned int    `json:"notScanned"`
+	EmptyCache bool   `json:"emptyCache,omitempty"`
+}
+
+//slackMessage payload accepted by Slack compatible incoming webhooks
+type slackMessage struct {
+	Text string `json:"text"`
+}
+
+//notifier posts check summaries to a webhook, and a Slack message to a Slack compatible webhook
+type notifier struct {
+	Url      string
+	SlackUrl string
+	Timeout  time.Duration
+	Retries  int
+	Backoff  time.Duration
+}
+
+//newNotifier nil when neither url is set
+func newNotifier(url, slackUrl string, timeout, retries string) (*notifier, error) {
+	if url == "" && slackUrl == "" {
+		return nil, nil
+	}
+	timeoutDuration, err := time.ParseDuration(timeout)
+	if err != nil || timeoutDuration <= 0 {
+		return nil, errors.New("Invalid notify timeout value:" + timeout)
+	}
+	retryCount, err := strconv.Atoi(retries)
+	if err != nil || retryCount < 0 {
+		return nil, errors.New("Invalid notify retry value:" + retries)
+	}
+	return &notifier{Url: url, SlackUrl: slackUrl, Timeout: timeoutDuration, Retries: retryCount, Backoff: time.Second}, nil
+}
+
+//newNotifySummary summarise a check report
+func newNotifySummary(event string, args []string, server string, duration time.Duration, report *checkReport) notifySummary {
+	summary := notifySummary{
+		Event:     event,
+		Server:    server,
+		Arguments: args,
+		Time:      time.Now().Format(time.RFC3339),
+		Duration:  duration.String(),
+	}
+	for _, status := range report.Statuses() {
+		summary.Total++
+		if !status.Scanned {
+			summary.NotScanned++
+		}
+		if status.isFailure() {
+			summary.Failed++
+		}
+	}
+	for _, repo := range report.Repos() {
+		summary.Repos = append(summary.Repos, notifyRepo{Name: repo.Repo, Total: repo.TotalCount, NotScanned: repo.NotIndexCount, EmptyCache: repo.EmptyCache})
+	}
+	return summary
+}
+
+func (s notifySummary) slackText() string {
+	var text strings.Builder
+	switch s.Event {
+	case "threshold":
+		fmt.Fprintf(&text, ":warning: indexcheck threshold of %d unscanned breached on %s\n", s.Threshold, s.Server)
+	case "check":
+		if s.Error != "" {
+			fmt.Fprintf(&text, ":x: indexcheck %s failed on %s after %s: %s\n", strings.Join(s.Arguments, " "), s.Server, s.Duration, s.Error)
+			break
+		}
+		fallthrough
+	default:
+		fmt.Fprintf(&text, "indexcheck %s finished on %s in %s\n", strings.Join(s.Arguments, " "), s.Server, s.Duration)
+	}
+	fmt.Fprintf(&text, "Scanned: %d / %d, not scanned: %d, failed: %d", s.Total-s.NotScanned, s.Total, s.NotScanned, s.Failed)
+	for _, repo := range s.Repos {
+		if repo.NotScanned > 0 {
+			fmt.Fprintf(&text, "\n• %s: %d / %d not scanned", repo.Name, repo.NotScanned, repo.Total)
+		}
+	}
+	return text.String()
+}
+
+//send post the summary to the notify url and the Slack message to the Slack url, one failing does not stop the other
+func (n *notifier) send(summary notifySummary) error {
+	var failed []string
+	if n.Url != "" {
+		if err := n.deliver(n.Url, summary); err != nil {
+			failed = append(failed, "summary:"+err.Error())
+		}
+	}
+	if n.SlackUrl != "" {
+		if err := n.deliver(n.SlackUrl, slackMessage{Text: summary.slackText()}); err != nil {
+			failed = append(failed, "slack:"+err.Error())
+		}
+	}
+	if len(failed) > 0 {
+		return errors.New(strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+//deliver post the payload to url, retrying on connection errors, 429 and 5xx responses
+func (n *notifier) deliver(url string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	client := http.Client{Timeout: n.Timeout}
+	for attempt := 0; ; attempt++ {
+		err = n.post(client, url, body)
+		if err == nil {
+			return nil
+		}
+		var permanent permanentNotifyError
+		if errors.As(err, &permanent) || attempt >= n.Retries {
+			return err
+		}
+		log.Warn("Notification failed, retrying, attempt ", attempt+1, ":", err)
+		time.Sleep(n.Backoff * time.Duration(attempt+1))
+	}
+}
+
+type permanentNotifyError struct {
+	error
+}
+
+func (n *notifier) post(client http.Client, url string, body []byte) error {
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	err = errors.New("notify url returned HTTP " + strconv.Itoa(resp.StatusCode))
+	if resp.StatusCode == 429 || resp.StatusCode >= 500 {
+		return err
+	}
+	return permanentNotifyError{err}
+}
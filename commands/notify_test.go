@@ -0,0 +1,140 @@
+package commands
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jfrog/jfrog-cli-core/v2/utils/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func testReport() *checkReport {
+	report := newCheckReport()
+	report.addStatus(artifactStatus{Repo: "generic-local", Path: "/a.tar.gz", Status: "scanned", Scanned: true})
+	report.addStatus(artifactStatus{Repo: "generic-local", Path: "/b.tar.gz", Status: "not scanned"})
+	report.addStatus(artifactStatus{Repo: "generic-local", Path: "/c.tar.gz", Status: "failed"})
+	report.addRepo(repoResult{Repo: "generic-local", TotalCount: 3, NotIndexCount: 2})
+	return report
+}
+
+func TestNotifyRetries(t *testing.T) {
+	var attempts int
+	var received notifySummary
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(body, &received)
+	}))
+	defer server.Close()
+
+	n, err := newNotifier(server.URL, "", "1s", "3")
+	assert.NoError(t, err)
+	n.Backoff = time.Millisecond
+	err = n.send(newNotifySummary("check", []string{"repo-single", "generic-local"}, "https://acme.jfrog.io/", time.Second, testReport()))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+	assert.Equal(t, 3, received.Total)
+	assert.Equal(t, 2, received.NotScanned)
+	assert.Equal(t, 1, received.Failed)
+	assert.Equal(t, []notifyRepo{{Name: "generic-local", Total: 3, NotScanned: 2}}, received.Repos)
+}
+
+func TestNotifyDoesNotRetryClientErrors(t *testing.T) {
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	n, _ := newNotifier(server.URL, "", "1s", "3")
+	n.Backoff = time.Millisecond
+	assert.Error(t, n.send(notifySummary{}))
+	assert.Equal(t, 1, attempts)
+}
+
+func TestNotifyTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	n, _ := newNotifier(server.URL, "", "20ms", "1")
+	n.Backoff = time.Millisecond
+	assert.Error(t, n.send(notifySummary{}))
+}
+
+func TestNotifySlack(t *testing.T) {
+	var received slackMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(body, &received)
+	}))
+	defer server.Close()
+
+	n, _ := newNotifier("", server.URL, "1s", "0")
+	summary := newNotifySummary("threshold", []string{"repo-all"}, "https://acme.jfrog.io/", time.Second, testReport())
+	summary.Threshold = 2
+	assert.NoError(t, n.send(summary))
+	assert.Contains(t, received.Text, "threshold of 2 unscanned breached")
+	assert.Contains(t, received.Text, "generic-local: 2 / 3 not scanned")
+}
+
+func TestNotifySummaryAndSlack(t *testing.T) {
+	var summary notifySummary
+	var message slackMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.URL.Path == "/slack" {
+			json.Unmarshal(body, &message)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.Unmarshal(body, &summary)
+	}))
+	defer server.Close()
+
+	n, _ := newNotifier(server.URL+"/summary", server.URL+"/slack", "1s", "0")
+	err := n.send(newNotifySummary("check", []string{"repo-all"}, "https://acme.jfrog.io/", time.Second, testReport()))
+	//a failing Slack webhook does not keep the summary from being sent
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "slack:")
+	}
+	assert.Equal(t, 3, summary.Total)
+	assert.Contains(t, message.Text, "repo-all finished")
+}
+
+func TestNotifyFailedCheck(t *testing.T) {
+	var summary notifySummary
+	var message slackMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		switch r.URL.Path {
+		case "/summary":
+			json.Unmarshal(body, &summary)
+		case "/slack":
+			json.Unmarshal(body, &message)
+		default:
+			//the build the check asks Artifactory for does not exist
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	n, _ := newNotifier(server.URL+"/summary", server.URL+"/slack", "1s", "0")
+	opts := &checkOptions{workers: 1, out: ioutil.Discard, report: newCheckReport(), notify: n}
+	err := runAndNotify([]string{"build-single", "app"}, &config.ServerDetails{Url: server.URL + "/", ArtifactoryUrl: server.URL + "/artifactory/"}, opts)
+	assert.Error(t, err)
+	//the failure is still sent, with why it failed
+	assert.Equal(t, []string{"build-single", "app"}, summary.Arguments)
+	assert.Equal(t, err.Error(), summary.Error)
+	assert.Contains(t, message.Text, "build-single app failed")
+}
@@ -0,0 +1,430 @@
+package commands
+
+import (
+	"image"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	ui "github.com/gizak/termui/v3"
+	"github.com/gizak/termui/v3/widgets"
+	"github.com/jfrog/jfrog-client-go/utils/log"
+	helpers "github.com/lorenyeung/indexcheck/utils"
+)
+
+//dashboardFrame one poll of the dashboard, also the data of text panel templates:
+//{{.Now.Format "15:04:05"}}, {{.Taken.Format "15:04:05"}} when the metrics were taken, {{.Error}} of the last poll when it failed, {{.LastUpdate}}, {{.Offset}}, {{.Response}}, {{.Compute}}, {{.Interval}}, {{.Server}}, {{.Count}},
+//{{.Value "expr"}} for the humanized value of the first series of an expression and {{.Raw "expr"}} for the number
+type dashboardFrame struct {
+	Now        time.Time
+	Taken      time.Time
+	Error      string
+	LastUpdate string
+	Offset     int
+	Response   time.Duration
+	Compute    time.Duration
+	Interval   int
+	Server     string
+	Count      int
+	ShowHidden bool
+	series     exprSeries
+	exprs      map[string]exprNode //parsed template expressions, kept by the panel across frames
+}
+
+func newDashboardFrame(data []helpers.Data) *dashboardFrame {
+	series := newExprSeries(data)
+	aliasRemoteConnections(series)
+	now := time.Now()
+	return &dashboardFrame{Now: now, Taken: now, Count: len(data), series: series}
+}
+
+//aliasRemoteConnections Artifactory versions that prefix the connection metrics with the remote repository,
+//e.g. docker_remote_jfrt_http_connections_leased_total, also get the unprefixed name with the repository as pool label
+func aliasRemoteConnections(series exprSeries) {
+	var names []string
+	for name := range series {
+		if i := strings.Index(name, "_jfrt_http_connections"); i > 0 {
+			names = append(names, name)
+		}
+	}
+	for _, name := range names {
+		i := strings.Index(name, "_jfrt_http_connections")
+		alias := name[i+1:]
+		for _, sample := range series[name] {
+			if _, ok := sample.Labels["pool"]; !ok {
+				sample.Labels = withLabel(sample.Labels, "pool", name[:i])
+			}
+			sample.Name = alias
+			series[alias] = append(series[alias], sample)
+		}
+	}
+}
+
+func (f *dashboardFrame) eval(node exprNode) []exprSample {
+	value, err := node.eval(f.series)
+	if err != nil {
+		log.Debug("Failed to evaluate panel expression:", err.Error())
+		return nil
+	}
+	return value.Samples
+}
+
+func (f *dashboardFrame) first(expr string) (exprSample, bool, error) {
+	node, ok := f.exprs[expr]
+	if !ok {
+		var err error
+		node, err = parseExpr(expr)
+		if err != nil {
+			return exprSample{}, false, err
+		}
+		if f.exprs != nil {
+			f.exprs[expr] = node
+		}
+	}
+	samples := f.eval(node)
+	if len(samples) == 0 {
+		return exprSample{}, false, nil
+	}
+	return samples[0], true, nil
+}
+
+//Value humanized value of the first series, n/a without data
+func (f *dashboardFrame) Value(expr string) (string, error) {
+	sample, ok, err := f.first(expr)
+	if err != nil || !ok {
+		return "n/a", err
+	}
+	return humanizeValue(sample.Name, strconv.FormatFloat(sample.Value, 'f', -1, 64)), nil
+}
+
+//Raw value of the first series for printf, NaN without data
+func (f *dashboardFrame) Raw(expr string) (float64, error) {
+	sample, ok, err := f.first(expr)
+	if err != nil || !ok {
+		return math.NaN(), err
+	}
+	return sample.Value, nil
+}
+
+//dashboardPanel a widget of the grid that redraws itself from a frame
+type dashboardPanel interface {
+	drawable() ui.Drawable
+	update(frame *dashboardFrame)
+}
+
+//keyedPanel a panel with a key of its own, key returns false for any other key
+type keyedPanel interface {
+	key(key string) bool
+}
+
+//newDashboardPanel window is how far back plots go
+func newDashboardPanel(config *panelConfig, window time.Duration) dashboardPanel {
+	switch config.Type {
+	case "gauge":
+		return newGaugePanel(config)
+	case "plot":
+		return newPlotPanel(config, window)
+	case "bar":
+		return newBarPanel(config)
+	case "list":
+		return newListPanel(config)
+	case "queue":
+		return newQueuePanel(config, window)
+	default:
+		return newTextPanel(config)
+	}
+}
+
+//seriesLabel the configured label followed by the labels of the series in distinct, all of them when distinct is nil,
+//e.g. leased{pool="npm-remote"}. Without a configured label, the series as metrics query prints it
+func seriesLabel(config seriesConfig, sample exprSample, distinct map[string]bool) string {
+	if config.Label == "" {
+		if label := sample.String(); label != "" {
+			return label
+		}
+		return config.Expr
+	}
+	labels := make(map[string]string)
+	for name, value := range sample.Labels {
+		if distinct == nil || distinct[name] {
+			labels[name] = value
+		}
+	}
+	if len(labels) == 0 {
+		return config.Label
+	}
+	return config.Label + "{" + formatLabels(labels) + "}"
+}
+
+//distinctLabels the labels that tell the samples apart, the ones with more than one value
+func distinctLabels(samples []exprSample) map[string]bool {
+	values := make(map[string]map[string]bool)
+	for _, sample := range samples {
+		for name, value := range sample.Labels {
+			if values[name] == nil {
+				values[name] = make(map[string]bool)
+			}
+			values[name][value] = true
+		}
+	}
+	distinct := make(map[string]bool)
+	for name := range values {
+		if len(values[name]) > 1 {
+			distinct[name] = true
+		}
+	}
+	return distinct
+}
+
+//seriesColor the configured color when the expression returns a single series, otherwise the i-th color of the palette
+//so that the series of one expression can be told apart
+func seriesColor(config seriesConfig, several bool, i int) ui.Color {
+	fallback := layoutPalette[i%len(layoutPalette)]
+	if several {
+		return fallback
+	}
+	color, _ := parseColor(config.Color, fallback)
+	return color
+}
+
+type gaugePanel struct {
+	config *panelConfig
+	gauge  *widgets.Gauge
+}
+
+func newGaugePanel(config *panelConfig) *gaugePanel {
+	gauge := widgets.NewGauge()
+	gauge.Title = config.Title
+	gauge.BarColor, _ = parseColor(config.Color, ui.ColorGreen)
+	gauge.LabelStyle = ui.NewStyle(ui.ColorBlue)
+	gauge.BorderStyle.Fg = ui.ColorWhite
+	return &gaugePanel{config: config, gauge: gauge}
+}
+
+func (p *gaugePanel) drawable() ui.Drawable { return p.gauge }
+
+func (p *gaugePanel) update(frame *dashboardFrame) {
+	p.gauge.Percent = 0
+	if samples := frame.eval(p.config.node); len(samples) > 0 {
+		p.gauge.Percent = clampPercent(samples[0].Value)
+	}
+}
+
+//plotPanel one line per series over the last window, resampled to the width of the plot when drawn
+type plotPanel struct {
+	*widgets.Plot
+	config *panelConfig
+	window time.Duration
+	lines  map[string]*seriesBuffer
+	order  []string
+	names  map[string]string //legend of every line, with the labels that tell it apart from the others
+	colors map[string]ui.Color
+	taken  time.Time //of the newest frame, the plot ends there
+}
+
+func newPlotPanel(config *panelConfig, window time.Duration) *plotPanel {
+	plot := widgets.NewPlot()
+	plot.Title = config.Title
+	if plot.Title != "" {
+		plot.Title += ", last " + formatWindow(window)
+	}
+	plot.DotMarkerRune = '.'
+	plot.AxesColor = ui.ColorWhite
+	plot.HorizontalScale = 1
+	return &plotPanel{Plot: plot, config: config, window: window, lines: make(map[string]*seriesBuffer), names: make(map[string]string), colors: make(map[string]ui.Color)}
+}
+
+func (p *plotPanel) drawable() ui.Drawable { return p }
+
+func (p *plotPanel) update(frame *dashboardFrame) {
+	p.Lock()
+	defer p.Unlock()
+	series := make([][]exprSample, len(p.config.Series))
+	var all []exprSample
+	for i, config := range p.config.Series {
+		series[i] = frame.eval(config.node)
+		all = append(all, series[i]...)
+	}
+	distinct := distinctLabels(all)
+	for i, config := range p.config.Series {
+		for _, sample := range series[i] {
+			//lines are kept by every label so they do not change when other series come and go
+			key := seriesLabel(config, sample, nil)
+			line, ok := p.lines[key]
+			if !ok {
+				line = newSeriesBuffer(p.window, time.Duration(frame.Interval)*time.Second)
+				p.colors[key] = seriesColor(config, len(series[i]) > 1, len(p.order))
+				p.lines[key] = line
+				p.order = append(p.order, key)
+			}
+			p.names[key] = seriesLabel(config, sample, distinct)
+			line.add(frame.Taken, sample.Value)
+		}
+	}
+	p.taken = frame.Taken
+
+	//lines that have not been seen for a window, or are ahead of a replay that went back in time, are dropped
+	order := p.order[:0]
+	for _, key := range p.order {
+		point, ok := p.lines[key].last()
+		if ok && !point.Time.After(frame.Taken) && frame.Taken.Sub(point.Time) <= p.window {
+			order = append(order, key)
+			continue
+		}
+		delete(p.lines, key)
+		delete(p.names, key)
+		delete(p.colors, key)
+	}
+	p.order = order
+}
+
+//Draw the grid locks the panel around it
+func (p *plotPanel) Draw(buf *ui.Buffer) {
+	//the y axis labels and the axis take 5 columns
+	columns := p.Inner.Dx() - 5
+	p.Data = make([][]float64, len(p.order))
+	p.LineColors = make([]ui.Color, len(p.order))
+	for i, key := range p.order {
+		p.Data[i] = p.lines[key].resample(p.taken, columns)
+		p.LineColors[i] = p.colors[key]
+	}
+	p.Plot.Draw(buf)
+	p.drawLegend(buf)
+}
+
+//drawLegend label and last value of every line in the top right corner, as many as fit
+func (p *plotPanel) drawLegend(buf *ui.Buffer) {
+	rows := p.Inner.Dy() - 2
+	width := p.Inner.Dx() / 2
+	if rows < 1 || width < 6 {
+		return
+	}
+	draw := func(row int, text string, color ui.Color) {
+		if len([]rune(text)) > width {
+			text = string([]rune(text)[:width-1]) + "…"
+		}
+		//a blank cell keeps the plot from running into the text
+		text = " " + text
+		buf.SetString(text, ui.NewStyle(color), image.Pt(p.Inner.Max.X-len([]rune(text)), p.Inner.Min.Y+row))
+	}
+	shown := len(p.order)
+	if shown > rows {
+		shown = rows - 1
+		draw(shown, "+"+strconv.Itoa(len(p.order)-shown)+" more", ui.ColorWhite)
+	}
+	for i, key := range p.order[:shown] {
+		text := "- " + p.names[key]
+		if point, ok := p.lines[key].last(); ok {
+			precision := 2
+			if point.Value == math.Trunc(point.Value) {
+				precision = 0
+			}
+			text += " " + strconv.FormatFloat(point.Value, 'f', precision, 64)
+		}
+		draw(i, text, p.colors[key])
+	}
+}
+
+type barPanel struct {
+	config *panelConfig
+	bar    *widgets.BarChart
+}
+
+func newBarPanel(config *panelConfig) *barPanel {
+	bar := widgets.NewBarChart()
+	bar.Title = config.Title
+	bar.BarWidth = 5
+	bar.LabelStyles = []ui.Style{ui.NewStyle(ui.ColorWhite)}
+	bar.NumStyles = []ui.Style{ui.NewStyle(ui.ColorBlack)}
+	return &barPanel{config: config, bar: bar}
+}
+
+func (p *barPanel) drawable() ui.Drawable { return p.bar }
+
+func (p *barPanel) update(frame *dashboardFrame) {
+	var data []float64
+	var labels []string
+	var colors []ui.Color
+	series := make([][]exprSample, len(p.config.Series))
+	var all []exprSample
+	for i, config := range p.config.Series {
+		series[i] = frame.eval(config.node)
+		all = append(all, series[i]...)
+	}
+	distinct := distinctLabels(all)
+	for i, config := range p.config.Series {
+		for _, sample := range series[i] {
+			colors = append(colors, seriesColor(config, len(series[i]) > 1, len(data)))
+			data = append(data, sample.Value)
+			labels = append(labels, seriesLabel(config, sample, distinct))
+		}
+	}
+	p.bar.Data, p.bar.Labels = data, labels
+	if len(colors) > 0 {
+		p.bar.BarColors = colors
+	}
+}
+
+//listPanel a row per series, sorted by its label
+type listPanel struct {
+	config *panelConfig
+	list   *widgets.List
+}
+
+func newListPanel(config *panelConfig) *listPanel {
+	list := widgets.NewList()
+	list.Title = config.Title
+	list.Rows = []string{}
+	color, _ := parseColor(config.Color, ui.ColorYellow)
+	list.TextStyle = ui.NewStyle(color)
+	list.WrapText = false
+	return &listPanel{config: config, list: list}
+}
+
+func (p *listPanel) drawable() ui.Drawable { return p.list }
+
+func (p *listPanel) update(frame *dashboardFrame) {
+	rows := []string{}
+	for _, sample := range frame.eval(p.config.node) {
+		label := formatLabels(sample.Labels)
+		if p.config.Label != "" {
+			label = sample.Labels[p.config.Label]
+		}
+		if p.config.hide != nil && !frame.ShowHidden && p.config.hide.MatchString(label) {
+			continue
+		}
+		rows = append(rows, label+" "+humanizeValue(sample.Name, strconv.FormatFloat(sample.Value, 'f', -1, 64)))
+	}
+	sort.Sort(Alphabetic(rows))
+	p.list.Rows = rows
+}
+
+type textPanel struct {
+	config    *panelConfig
+	paragraph *widgets.Paragraph
+}
+
+func newTextPanel(config *panelConfig) *textPanel {
+	paragraph := widgets.NewParagraph()
+	paragraph.Title = config.Title
+	paragraph.Text = "Initializing"
+	color, _ := parseColor(config.Color, ui.ColorWhite)
+	paragraph.TextStyle = ui.NewStyle(color)
+	return &textPanel{config: config, paragraph: paragraph}
+}
+
+func (p *textPanel) drawable() ui.Drawable { return p.paragraph }
+
+func (p *textPanel) update(frame *dashboardFrame) {
+	var text strings.Builder
+	//the frame is shared by every panel, the parsed expressions are the panel's own
+	view := *frame
+	view.exprs = p.config.exprs
+	if err := p.config.text.Execute(&text, &view); err != nil {
+		p.paragraph.Text = "Failed to render panel: " + err.Error()
+		return
+	}
+	p.paragraph.Text = text.String()
+}
@@ -0,0 +1,261 @@
+package commands
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	ui "github.com/gizak/termui/v3"
+	"github.com/gizak/termui/v3/widgets"
+)
+
+//queueRateWindow how far back the rate of change of a queue looks, short enough to follow a backlog clearing
+const queueRateWindow = time.Minute
+
+var sparkRunes = []rune("▁▂▃▄▅▆▇█")
+
+//queueRow the state of one queue at the last poll
+type queueRow struct {
+	Name  string
+	Depth float64
+	Rate  float64 //messages per second, positive when the queue grows
+	Known bool    //false until there are two polls to compute a rate from
+	Trend []float64
+}
+
+//queueColumns widths of the columns after the queue name, the name takes what is left
+var queueColumns = []struct {
+	Name  string
+	Width int
+}{{"DEPTH", 10}, {"RATE", 11}, {"TREND", 10}, {"DRAIN", 9}}
+
+//queueColumnsDropped the order columns are left out in when the panel is too narrow for all of them
+var queueColumnsDropped = []string{"TREND", "RATE", "DRAIN", "DEPTH"}
+
+//queuePanel a row per queue with its depth, rate of change, a sparkline and when it will be empty
+type queuePanel struct {
+	*widgets.Table
+	config  *panelConfig
+	window  time.Duration
+	buffers map[string]*seriesBuffer
+	rows    []queueRow
+	growth  bool //sort by rate of change instead of depth
+}
+
+func newQueuePanel(config *panelConfig, window time.Duration) *queuePanel {
+	table := widgets.NewTable()
+	table.RowSeparator = false
+	color, _ := parseColor(config.Color, ui.ColorYellow)
+	table.TextStyle = ui.NewStyle(color)
+	p := &queuePanel{Table: table, config: config, window: window, buffers: make(map[string]*seriesBuffer), growth: config.Sort == "growth"}
+	p.sortRows()
+	return p
+}
+
+func (p *queuePanel) drawable() ui.Drawable { return p }
+
+func (p *queuePanel) update(frame *dashboardFrame) {
+	p.Lock()
+	defer p.Unlock()
+	p.rows = nil
+	seen := make(map[string]bool)
+	for _, sample := range frame.eval(p.config.node) {
+		name := formatLabels(sample.Labels)
+		if p.config.Label != "" {
+			name = sample.Labels[p.config.Label]
+		}
+		if p.config.hide != nil && !frame.ShowHidden && p.config.hide.MatchString(name) {
+			continue
+		}
+		seen[name] = true
+		buffer, ok := p.buffers[name]
+		if !ok {
+			buffer = newSeriesBuffer(p.window, time.Duration(frame.Interval)*time.Second)
+			p.buffers[name] = buffer
+		}
+		buffer.add(frame.Taken, sample.Value)
+		points := buffer.snapshot()
+		row := queueRow{Name: name, Depth: sample.Value, Trend: queueTrend(points, frame.Taken, 10)}
+		row.Rate, row.Known = queueRate(points)
+		p.rows = append(p.rows, row)
+	}
+	//queues that are gone, or now hidden, take their history with them
+	for name := range p.buffers {
+		if !seen[name] {
+			delete(p.buffers, name)
+		}
+	}
+	p.sortRows()
+}
+
+//key switch between sorting by depth and by growth
+func (p *queuePanel) key(key string) bool {
+	if key != p.config.Key {
+		return false
+	}
+	p.Lock()
+	defer p.Unlock()
+	p.growth = !p.growth
+	p.sortRows()
+	return true
+}
+
+//sortRows and title the panel after the order
+func (p *queuePanel) sortRows() {
+	sortQueues(p.rows, p.growth)
+	sortBy := "depth"
+	if p.growth {
+		sortBy = "growth"
+	}
+	p.Title = p.config.Title + " by " + sortBy
+	if p.config.Key != "" {
+		p.Title += " (" + p.config.Key + ": sort)"
+	}
+}
+
+//Draw the columns that fit the panel, a growing queue in red and a draining one in green. The grid locks the panel around it
+func (p *queuePanel) Draw(buf *ui.Buffer) {
+	dropped := make(map[string]bool)
+	width := func() int {
+		//every column ends with a separator
+		used := 0
+		for _, column := range queueColumns {
+			if !dropped[column.Name] {
+				used += column.Width + 1
+			}
+		}
+		return p.Inner.Dx() - used - 1
+	}
+	for _, name := range queueColumnsDropped {
+		if width() >= 8 {
+			break
+		}
+		dropped[name] = true
+	}
+
+	header := []string{"QUEUE"}
+	p.ColumnWidths = []int{width()}
+	for _, column := range queueColumns {
+		if !dropped[column.Name] {
+			header = append(header, column.Name)
+			p.ColumnWidths = append(p.ColumnWidths, column.Width)
+		}
+	}
+	p.Rows = [][]string{header}
+	p.RowStyles = map[int]ui.Style{0: ui.NewStyle(ui.ColorWhite, ui.ColorClear, ui.ModifierBold)}
+	for i, row := range p.rows {
+		rate := ""
+		if row.Known {
+			rate = fmt.Sprintf("%+.1f/s", row.Rate)
+		}
+		values := map[string]string{"DEPTH": strconv.FormatFloat(row.Depth, 'f', -1, 64), "RATE": rate, "TREND": sparkline(row.Trend), "DRAIN": drainETA(row)}
+		cells := []string{row.Name}
+		for _, column := range queueColumns {
+			if !dropped[column.Name] {
+				cells = append(cells, values[column.Name])
+			}
+		}
+		p.Rows = append(p.Rows, cells)
+		switch {
+		case row.Known && row.Rate > 0:
+			p.RowStyles[i+1] = ui.NewStyle(ui.ColorRed)
+		case row.Known && row.Rate < 0:
+			p.RowStyles[i+1] = ui.NewStyle(ui.ColorGreen)
+		}
+	}
+	p.Table.Draw(buf)
+}
+
+//sortQueues deepest or fastest growing first, by name when equal
+func sortQueues(rows []queueRow, growth bool) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		a, b := rows[i].Depth, rows[j].Depth
+		if growth {
+			a, b = rows[i].Rate, rows[j].Rate
+		}
+		if a != b {
+			return a > b
+		}
+		return Alphabetic{rows[i].Name, rows[j].Name}.Less(0, 1)
+	})
+}
+
+//queueRate change per second between the newest point and the oldest one of the last queueRateWindow
+func queueRate(points []timePoint) (float64, bool) {
+	if len(points) < 2 {
+		return 0, false
+	}
+	last := points[len(points)-1]
+	first := points[len(points)-2]
+	for i := len(points) - 2; i >= 0 && last.Time.Sub(points[i].Time) <= queueRateWindow; i-- {
+		first = points[i]
+	}
+	elapsed := last.Time.Sub(first.Time).Seconds()
+	if elapsed <= 0 {
+		return 0, false
+	}
+	return (last.Value - first.Value) / elapsed, true
+}
+
+//drainETA how long the queue takes to empty at its current rate
+func drainETA(row queueRow) string {
+	switch {
+	case row.Depth <= 0:
+		return "empty"
+	case !row.Known:
+		return ""
+	case row.Rate >= 0:
+		return "never"
+	}
+	seconds := row.Depth / -row.Rate
+	if seconds > (30 * 24 * time.Hour).Seconds() {
+		return "> 30d"
+	}
+	return (time.Duration(seconds) * time.Second).String()
+}
+
+//queueTrend the points from the first one up to now in columns of equal duration, a column holds
+//the newest point polled before its end. Nothing is made up for before the queue was first seen
+func queueTrend(points []timePoint, now time.Time, columns int) []float64 {
+	if len(points) == 0 {
+		return nil
+	}
+	values := make([]float64, columns)
+	from := points[0].Time
+	step := now.Sub(from) / time.Duration(columns)
+	next := 0
+	for i := range values {
+		end := from.Add(step * time.Duration(i+1))
+		if i == columns-1 {
+			end = now
+		}
+		for next < len(points) && !points[next].Time.After(end) {
+			next++
+		}
+		values[i] = points[next-1].Value
+	}
+	return values
+}
+
+//sparkline a block character per value, scaled from the smallest to the largest one
+func sparkline(values []float64) string {
+	if len(values) == 0 {
+		return ""
+	}
+	min, max := values[0], values[0]
+	for _, value := range values {
+		min = math.Min(min, value)
+		max = math.Max(max, value)
+	}
+	spark := make([]rune, len(values))
+	for i, value := range values {
+		level := 0
+		if max > min {
+			level = int(math.Round((value - min) / (max - min) * float64(len(sparkRunes)-1)))
+		}
+		spark[i] = sparkRunes[level]
+	}
+	return string(spark)
+}
@@ -0,0 +1,111 @@
+package commands
+
+import (
+	"testing"
+	"time"
+
+	ui "github.com/gizak/termui/v3"
+	helpers "github.com/lorenyeung/indexcheck/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueueRate(t *testing.T) {
+	start := time.Date(2021, 3, 4, 10, 0, 0, 0, time.UTC)
+	_, known := queueRate([]timePoint{{Time: start, Value: 10}})
+	assert.False(t, known)
+
+	//only the last minute counts, the jump before it does not
+	rate, known := queueRate([]timePoint{
+		{Time: start, Value: 1000},
+		{Time: start.Add(30 * time.Second), Value: 100},
+		{Time: start.Add(60 * time.Second), Value: 80},
+		{Time: start.Add(90 * time.Second), Value: 40},
+	})
+	assert.True(t, known)
+	assert.Equal(t, float64(-1), rate)
+}
+
+func TestDrainETA(t *testing.T) {
+	assert.Equal(t, "empty", drainETA(queueRow{Depth: 0, Known: true, Rate: -1}))
+	assert.Equal(t, "", drainETA(queueRow{Depth: 10}))
+	assert.Equal(t, "never", drainETA(queueRow{Depth: 10, Known: true, Rate: 0}))
+	assert.Equal(t, "1m30s", drainETA(queueRow{Depth: 180, Known: true, Rate: -2}))
+	assert.Equal(t, "> 30d", drainETA(queueRow{Depth: 1e9, Known: true, Rate: -0.001}))
+}
+
+func TestSparkline(t *testing.T) {
+	assert.Equal(t, "▁▁▅█", sparkline([]float64{0, 1, 50, 100}))
+	assert.Equal(t, "▁▁", sparkline([]float64{0, 0}))
+	//scaled between the values, a queue that drains from 1200 to 1000 drops to the bottom
+	assert.Equal(t, "█▅▁", sparkline([]float64{1200, 1100, 1000}))
+	assert.Equal(t, "", sparkline(nil))
+}
+
+func TestQueueTrend(t *testing.T) {
+	start := time.Date(2021, 3, 4, 10, 0, 0, 0, time.UTC)
+	assert.Nil(t, queueTrend(nil, start, 4))
+	//the trend starts at the first point instead of 0
+	points := []timePoint{{Time: start, Value: 1200}, {Time: start.Add(20 * time.Second), Value: 1100}, {Time: start.Add(40 * time.Second), Value: 1000}}
+	assert.Equal(t, []float64{1200, 1100, 1100, 1000}, queueTrend(points, start.Add(40*time.Second), 4))
+	assert.Equal(t, []float64{1200, 1200}, queueTrend(points[:1], start, 2))
+}
+
+func TestSortQueues(t *testing.T) {
+	rows := []queueRow{{Name: "b", Depth: 5, Rate: 3}, {Name: "a", Depth: 5, Rate: -1}, {Name: "c", Depth: 9, Rate: 0}}
+	sortQueues(rows, false)
+	assert.Equal(t, []string{"c", "a", "b"}, []string{rows[0].Name, rows[1].Name, rows[2].Name})
+	sortQueues(rows, true)
+	assert.Equal(t, []string{"b", "c", "a"}, []string{rows[0].Name, rows[1].Name, rows[2].Name})
+}
+
+func TestQueuePanel(t *testing.T) {
+	root, err := parseLayout([]byte("panel: {type: queue, title: Queues, expr: queue_messages_total, label: queue_name, hide: Retry, key: s}"))
+	assert.NoError(t, err)
+	board := newDashboard(root, 5*time.Minute)
+	queues := board.panels[0].(*queuePanel)
+
+	queue := func(index, persist string) []helpers.Data {
+		return []helpers.Data{{Name: "queue_messages_total", Metric: []helpers.Metrics{
+			{Value: index, Labels: map[string]string{"queue_name": "Index"}},
+			{Value: "7", Labels: map[string]string{"queue_name": "IndexRetry"}},
+			{Value: persist, Labels: map[string]string{"queue_name": "Persist"}},
+		}}}
+	}
+	frame := newDashboardFrame(queue("1200", "10"))
+	frame.Interval = 10
+	board.update(frame)
+	frame = newDashboardFrame(queue("1000", "30"))
+	frame.Interval = 10
+	frame.Taken = frame.Taken.Add(10 * time.Second)
+	board.update(frame)
+
+	queues.SetRect(0, 0, 70, 6)
+	queues.Draw(ui.NewBuffer(queues.GetRect()))
+	assert.Equal(t, "Queues by depth (s: sort)", queues.Title)
+	assert.Equal(t, []string{"QUEUE", "DEPTH", "RATE", "TREND", "DRAIN"}, queues.Rows[0])
+	assert.Equal(t, []string{"Index", "1000", "-20.0/s", "█████████▁", "50s"}, queues.Rows[1])
+	assert.Equal(t, []string{"Persist", "30", "+2.0/s", "▁▁▁▁▁▁▁▁▁█", "never"}, queues.Rows[2])
+	assert.Equal(t, ui.NewStyle(ui.ColorRed), queues.RowStyles[2])
+
+	assert.False(t, board.key("x"))
+	assert.True(t, board.key("s"))
+	assert.Equal(t, "Queues by growth (s: sort)", queues.Title)
+	queues.Draw(ui.NewBuffer(queues.GetRect()))
+	assert.Equal(t, "Persist", queues.Rows[1][0])
+
+	//a narrow panel keeps the name, depth and drain time
+	queues.SetRect(0, 0, 32, 6)
+	queues.Draw(ui.NewBuffer(queues.GetRect()))
+	assert.Equal(t, []string{"QUEUE", "DEPTH", "DRAIN"}, queues.Rows[0])
+
+	//a queue that is no longer scraped loses its buffer
+	frame = newDashboardFrame([]helpers.Data{{Name: "queue_messages_total", Metric: []helpers.Metrics{
+		{Value: "900", Labels: map[string]string{"queue_name": "Index"}},
+		{Value: "7", Labels: map[string]string{"queue_name": "IndexRetry"}},
+	}}})
+	frame.Interval = 10
+	frame.Taken = frame.Taken.Add(20 * time.Second)
+	board.update(frame)
+	assert.Contains(t, queues.buffers, "Index")
+	assert.NotContains(t, queues.buffers, "Persist")
+}
@@ -0,0 +1,103 @@
+package commands
+
+import (
+	"sort"
+	"sync"
+)
+
+//artifactStatus scan status of a single artifact, or of a build number
+type artifactStatus struct {
+	Repo     string `json:"repo"`
+	Path     string `json:"path"`
+	ScanType string `json:"scanType"`
+	Status   string `json:"status"`
+	Scanned  bool   `json:"scanned"`
+}
+
+func (s artifactStatus) key() string {
+	return s.Repo + ":" + s.Path
+}
+
+//isFailure statuses that need attention rather than time
+func (s artifactStatus) isFailure() bool {
+	return s.Status == "failed" || s.Status == "Failed getting details"
+}
+
+//checkReport everything found by a check run, workers add to it concurrently
+type checkReport struct {
+	mu       sync.Mutex
+	statuses map[string]artifactStatus
+	repos    []repoResult
+	queued   int
+	done     int
+}
+
+func newCheckReport() *checkReport {
+	return &checkReport{statuses: make(map[string]artifactStatus)}
+}
+
+func (r *checkReport) addStatus(status artifactStatus) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.statuses[status.key()] = status
+}
+
+func (r *checkReport) addRepo(result repoResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.repos = append(r.repos, result)
+}
+
+//addQueued artifacts about to be checked, the total grows as repositories are listed
+func (r *checkReport) addQueued(count int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.queued += count
+}
+
+func (r *checkReport) addDone() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.done++
+}
+
+//Progress checked and queued artifact counts
+func (r *checkReport) Progress() (int, int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.done, r.queued
+}
+
+//Statuses every checked artifact, sorted by repository and path
+func (r *checkReport) Statuses() []artifactStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	statuses := make([]artifactStatus, 0, len(r.statuses))
+	for _, status := range r.statuses {
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].key() < statuses[j].key() })
+	return statuses
+}
+
+//Repos totals of every checked repository and build
+func (r *checkReport) Repos() []repoResult {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	repos := make([]repoResult, len(r.repos))
+	copy(repos, r.repos)
+	return repos
+}
+
+//Totals number of checked and unscanned artifacts
+func (r *checkReport) Totals() (int, int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var notIndexCount int
+	for _, status := range r.statuses {
+		if !status.Scanned {
+			notIndexCount++
+		}
+	}
+	return len(r.statuses), notIndexCount
+}
@@ -0,0 +1,71 @@
+package commands
+
+import (
+	"strings"
+
+	"github.com/jfrog/jfrog-client-go/utils/log"
+)
+
+const (
+	repoTypeLocal     = "local"
+	repoTypeRemote    = "remote"
+	repoTypeFederated = "federated"
+	repoTypeCache     = "cache"
+	cacheSuffix       = "-cache"
+)
+
+//resolvedRepo where the artifacts of a repository marked for indexing are actually stored
+type resolvedRepo struct {
+	Name        string //name as marked for indexing
+	StorageName string //name used to list and scan artifacts
+	PkgType     string
+	Type        string
+}
+
+//isRemote remote repositories only hold what has been cached, so an empty file list is expected
+func (r resolvedRepo) isRemote() bool {
+	return r.Type == repoTypeRemote || r.Type == repoTypeCache
+}
+
+//lookupIndexedRepo find a repository marked for indexing, a remote cache can be given by its -cache name
+func lookupIndexedRepo(repoName string, indexedMap map[string]IndexedRepo) (IndexedRepo, bool) {
+	if repo, ok := indexedMap[repoName]; ok {
+		return repo, true
+	}
+	if strings.HasSuffix(repoName, cacheSuffix) {
+		remote, ok := indexedMap[strings.TrimSuffix(repoName, cacheSuffix)]
+		if ok && strings.ToLower(remote.Type) == repoTypeRemote {
+			return IndexedRepo{Name: repoName, PkgType: remote.PkgType, Type: repoTypeCache}, true
+		}
+	}
+	return IndexedRepo{}, false
+}
+
+//resolveRepoType work out the storage name of a repository from its type
+func resolveRepoType(repo IndexedRepo) resolvedRepo {
+	resolved := resolvedRepo{
+		Name:        repo.Name,
+		StorageName: repo.Name,
+		PkgType:     strings.ToLower(repo.PkgType),
+		Type:        strings.ToLower(repo.Type),
+	}
+	switch resolved.Type {
+	case repoTypeLocal, repoTypeFederated:
+		//artifacts are stored under the repository itself
+	case repoTypeRemote:
+		//artifacts of a remote are stored in its cache, unless the cache was already given
+		if strings.HasSuffix(repo.Name, cacheSuffix) {
+			resolved.Type = repoTypeCache
+		} else {
+			resolved.StorageName = repo.Name + cacheSuffix
+		}
+	case repoTypeCache:
+		if !strings.HasSuffix(repo.Name, cacheSuffix) {
+			resolved.StorageName = repo.Name + cacheSuffix
+		}
+	default:
+		log.Warn("Unknown repository type ", repo.Type, " for ", repo.Name, ", treating it as local")
+		resolved.Type = repoTypeLocal
+	}
+	return resolved
+}
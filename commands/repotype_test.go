@@ -0,0 +1,40 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveRepoType(t *testing.T) {
+	tests := []struct {
+		repo        IndexedRepo
+		storageName string
+		repoType    string
+	}{
+		{IndexedRepo{Name: "npm-local", PkgType: "Npm", Type: "local"}, "npm-local", repoTypeLocal},
+		{IndexedRepo{Name: "npm-remote", PkgType: "Npm", Type: "remote"}, "npm-remote-cache", repoTypeRemote},
+		{IndexedRepo{Name: "npm-remote-cache", PkgType: "Npm", Type: "remote"}, "npm-remote-cache", repoTypeCache},
+		{IndexedRepo{Name: "npm-fed", PkgType: "Npm", Type: "Federated"}, "npm-fed", repoTypeFederated},
+		{IndexedRepo{Name: "npm-odd", PkgType: "Npm", Type: "unknown"}, "npm-odd", repoTypeLocal},
+	}
+	for _, test := range tests {
+		resolved := resolveRepoType(test.repo)
+		assert.Equal(t, test.storageName, resolved.StorageName, test.repo.Name)
+		assert.Equal(t, test.repoType, resolved.Type, test.repo.Name)
+		assert.Equal(t, "npm", resolved.PkgType, test.repo.Name)
+	}
+}
+
+func TestLookupIndexedRepo(t *testing.T) {
+	indexedMap := map[string]IndexedRepo{
+		"npm-remote": {Name: "npm-remote", PkgType: "npm", Type: "remote"},
+		"npm-local":  {Name: "npm-local", PkgType: "npm", Type: "local"},
+	}
+	repo, ok := lookupIndexedRepo("npm-remote-cache", indexedMap)
+	assert.True(t, ok)
+	assert.Equal(t, IndexedRepo{Name: "npm-remote-cache", PkgType: "npm", Type: repoTypeCache}, repo)
+
+	_, ok = lookupIndexedRepo("npm-local-cache", indexedMap)
+	assert.False(t, ok)
+}
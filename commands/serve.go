@@ -0,0 +1,355 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jfrog/jfrog-cli-core/v2/plugins/components"
+	"github.com/jfrog/jfrog-cli-core/v2/utils/config"
+	"github.com/jfrog/jfrog-client-go/utils/log"
+	helpers "github.com/lorenyeung/indexcheck/utils"
+)
+
+func GetServeCommand() components.Command {
+	return components.Command{
+		Name:        "serve",
+		Description: "Serve the check engine over a REST API.",
+		Aliases:     []string{"s"},
+		Arguments:   getServeArguments(),
+		Flags:       getServeFlags(),
+		EnvVars:     getServeEnvVar(),
+		Action: func(c *components.Context) error {
+			return ServeCmd(c)
+		},
+	}
+}
+
+func getServeArguments() []components.Argument {
+	return []components.Argument{}
+}
+
+//getServeFlags defaults for checks started over the API
+func getServeFlags() []components.Flag {
+	flags := []components.Flag{
+		components.StringFlag{
+			Name:         "listen",
+			Description:  "Address to serve the API on",
+			DefaultValue: ":9410",
+		},
+		components.StringFlag{
+			Name:         "max-checks",
+			Description:  "Checks allowed to run at once, more are refused with 429",
+			DefaultValue: "2",
+		},
+		components.StringFlag{
+			Name:         "keep",
+			Description:  "How long results of finished checks are kept e.g. 30m, 24h",
+			DefaultValue: "1h",
+		},
+	}
+	for _, flag := range getCheckFlags() {
+		switch flag.GetName() {
+		case "worker", "latest", "project", "deep":
+			flags = append(flags, flag)
+		}
+	}
+	return flags
+}
+
+func getServeEnvVar() []components.EnvVar {
+	return []components.EnvVar{}
+}
+
+//checkRequest body of POST /checks
+type checkRequest struct {
+	Type    string `json:"type"` //repo, path or build
+	Target  string `json:"target"`
+	Path    string `json:"path,omitempty"`
+	Deep    *bool  `json:"deep,omitempty"`
+	Latest  *int   `json:"latest,omitempty"`
+	Project string `json:"project,omitempty"`
+}
+
+//args check arguments equivalent to the request
+func (r checkRequest) args() ([]string, error) {
+	if r.Target == "" {
+		return nil, errors.New("missing target")
+	}
+	switch r.Type {
+	case "repo":
+		return []string{"repo-single", r.Target}, nil
+	case "path":
+		if r.Path == "" {
+			return nil, errors.New("missing path")
+		}
+		return []string{"repo-path", r.Target, r.Path}, nil
+	case "build":
+		return []string{"build-single", r.Target}, nil
+	default:
+		return nil, errors.New("unsupported check type:" + r.Type + ", expected repo, path or build")
+	}
+}
+
+//serveCheck a check started over the API
+type serveCheck struct {
+	ID       string
+	Request  checkRequest
+	State    string
+	Error    string
+	Started  time.Time
+	Finished time.Time
+	report   *checkReport
+}
+
+//checkResponse body of GET /checks/{id}
+type checkResponse struct {
+	ID       string           `json:"id"`
+	Request  checkRequest     `json:"request"`
+	State    string           `json:"state"`
+	Error    string           `json:"error,omitempty"`
+	Started  string           `json:"started"`
+	Finished string           `json:"finished,omitempty"`
+	Progress checkProgress    `json:"progress"`
+	Summary  notifySummary    `json:"summary"`
+	Statuses []artifactStatus `json:"statuses"`
+}
+
+type checkProgress struct {
+	Done   int `json:"done"`
+	Queued int `json:"queued"`
+}
+
+//server runs up to maxRunning checks in the background and keeps their results in memory for keep after they finish
+type server struct {
+	mu         sync.Mutex
+	checks     map[string]*serveCheck
+	nextID     int
+	running    int
+	maxRunning int
+	keep       time.Duration
+	opts       *checkOptions
+	config     *config.ServerDetails
+	run        func(args []string, opts *checkOptions) error
+	repos      func(project string) ([]IndexedRepo, error)
+}
+
+func newServer(config *config.ServerDetails, opts *checkOptions, maxRunning int, keep time.Duration) *server {
+	return &server{
+		checks:     make(map[string]*serveCheck),
+		maxRunning: maxRunning,
+		keep:       keep,
+		opts:       opts,
+		config:     config,
+		run: func(args []string, opts *checkOptions) error {
+			return runChecks(args, config, opts)
+		},
+		repos: func(project string) ([]IndexedRepo, error) {
+			indexList := CheckTypeAndRepoParams(config)
+			if project == "" {
+				return indexList, nil
+			}
+			return filterProjectRepos(indexList, project, config)
+		},
+	}
+}
+
+func ServeCmd(c *components.Context) error {
+	config, err := helpers.GetConfig(helpers.MetricsXray)
+	if err != nil {
+		return err
+	}
+	opts, err := getCheckOptions(c)
+	if err != nil {
+		return err
+	}
+	maxRunning, err := strconv.Atoi(c.GetStringFlagValue("max-checks"))
+	if err != nil || maxRunning <= 0 {
+		return errors.New("Invalid max-checks value:" + c.GetStringFlagValue("max-checks"))
+	}
+	keep, err := time.ParseDuration(c.GetStringFlagValue("keep"))
+	if err != nil || keep <= 0 {
+		return errors.New("Invalid keep value:" + c.GetStringFlagValue("keep"))
+	}
+	s := newServer(config, opts, maxRunning, keep)
+	fmt.Println("Serving checks on", c.GetStringFlagValue("listen"))
+	return http.ListenAndServe(c.GetStringFlagValue("listen"), s.handler())
+}
+
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/checks", s.handleChecks)
+	mux.HandleFunc("/checks/", s.handleCheck)
+	mux.HandleFunc("/repos", s.handleRepos)
+	return mux
+}
+
+//handleChecks POST /checks starts a check
+func (s *server) handleChecks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("use POST to start a check"))
+		return
+	}
+	var request checkRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errors.New("invalid check request:"+err.Error()))
+		return
+	}
+	args, err := request.args()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if request.Path != "" && !strings.HasPrefix(request.Path, "/") {
+		request.Path = "/" + request.Path
+		args[2] = request.Path
+	}
+
+	opts := s.opts.rerun(ioutil.Discard)
+	opts.reindex = false
+	if request.Deep != nil {
+		opts.deep = *request.Deep
+	}
+	//the --deep default only applies to builds
+	if opts.deep && request.Type != "build" {
+		if request.Deep != nil {
+			writeError(w, http.StatusBadRequest, errors.New("deep only applies to build checks"))
+			return
+		}
+		opts.deep = false
+	}
+	if request.Latest != nil {
+		opts.latest = *request.Latest
+	}
+	if request.Project != "" {
+		opts.project = request.Project
+	}
+
+	s.mu.Lock()
+	s.prune()
+	if s.running >= s.maxRunning {
+		s.mu.Unlock()
+		w.Header().Set("Retry-After", "60")
+		writeError(w, http.StatusTooManyRequests, errors.New(strconv.Itoa(s.running)+" checks are already running, try again later"))
+		return
+	}
+	s.running++
+	s.nextID++
+	check := &serveCheck{ID: strconv.Itoa(s.nextID), Request: request, State: "running", Started: time.Now(), report: opts.report}
+	s.checks[check.ID] = check
+	s.mu.Unlock()
+
+	go func() {
+		err := s.run(args, opts)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.running--
+		check.Finished = time.Now()
+		if err != nil {
+			log.Warn("Check ", check.ID, " failed:", err)
+			check.State = "failed"
+			check.Error = err.Error()
+			return
+		}
+		check.State = "done"
+	}()
+
+	w.Header().Set("Location", "/checks/"+check.ID)
+	writeJSON(w, http.StatusAccepted, s.response(check, false))
+}
+
+//handleCheck GET /checks/{id} progress and results, ?all=true includes scanned artifacts
+func (s *server) handleCheck(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("use GET to get a check"))
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, "/checks/")
+	s.mu.Lock()
+	s.prune()
+	check, ok := s.checks[id]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, errors.New("check "+id+" not found"))
+		return
+	}
+	writeJSON(w, http.StatusOK, s.response(check, r.URL.Query().Get("all") == "true"))
+}
+
+//handleRepos GET /repos repositories marked for indexing, ?project= scopes them to a project
+func (s *server) handleRepos(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("use GET to list repositories"))
+		return
+	}
+	project := r.URL.Query().Get("project")
+	if project == "" {
+		project = s.opts.project
+	}
+	repos, err := s.repos(project)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	if repos == nil {
+		repos = []IndexedRepo{}
+	}
+	writeJSON(w, http.StatusOK, repos)
+}
+
+//prune drop the checks that finished more than keep ago, the caller holds the lock
+func (s *server) prune() {
+	for id, check := range s.checks {
+		if !check.Finished.IsZero() && time.Since(check.Finished) > s.keep {
+			delete(s.checks, id)
+		}
+	}
+}
+
+func (s *server) response(check *serveCheck, all bool) checkResponse {
+	s.mu.Lock()
+	response := checkResponse{
+		ID:      check.ID,
+		Request: check.Request,
+		State:   check.State,
+		Error:   check.Error,
+		Started: check.Started.Format(time.RFC3339),
+	}
+	duration := time.Since(check.Started)
+	if !check.Finished.IsZero() {
+		response.Finished = check.Finished.Format(time.RFC3339)
+		duration = check.Finished.Sub(check.Started)
+	}
+	s.mu.Unlock()
+
+	response.Progress.Done, response.Progress.Queued = check.report.Progress()
+	args, _ := check.Request.args()
+	response.Summary = newNotifySummary("check", args, s.config.Url, duration, check.report)
+	response.Statuses = []artifactStatus{}
+	for _, status := range check.report.Statuses() {
+		if all || !status.Scanned {
+			response.Statuses = append(response.Statuses, status)
+		}
+	}
+	return response
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		log.Warn("Failed to write response:", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,136 @@
+package commands
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jfrog/jfrog-cli-core/v2/utils/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func testServer(t *testing.T) (*httptest.Server, chan []string) {
+	calls := make(chan []string, 1)
+	s := newServer(&config.ServerDetails{Url: "https://acme.jfrog.io/"}, &checkOptions{workers: 1, out: ioutil.Discard, report: newCheckReport()}, 2, time.Hour)
+	s.run = func(args []string, opts *checkOptions) error {
+		calls <- args
+		opts.report.addQueued(2)
+		opts.report.addStatus(artifactStatus{Repo: "generic-local", Path: "/a.tar.gz", Status: "scanned", Scanned: true})
+		opts.report.addStatus(artifactStatus{Repo: "generic-local", Path: "/b.tar.gz", Status: "not scanned"})
+		opts.report.addDone()
+		opts.report.addDone()
+		return nil
+	}
+	s.repos = func(project string) ([]IndexedRepo, error) {
+		return []IndexedRepo{{Name: "generic-local", PkgType: "generic", Type: "local"}}, nil
+	}
+	return httptest.NewServer(s.handler()), calls
+}
+
+func getCheck(t *testing.T, url string) checkResponse {
+	resp, err := http.Get(url)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	var check checkResponse
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&check))
+	return check
+}
+
+func TestServeCheck(t *testing.T) {
+	ts, calls := testServer(t)
+	defer ts.Close()
+
+	resp, err := http.Post(ts.URL+"/checks", "application/json", strings.NewReader(`{"type":"path","target":"generic-local","path":"centos"}`))
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+	assert.Equal(t, "/checks/1", resp.Header.Get("Location"))
+	assert.Equal(t, []string{"repo-path", "generic-local", "/centos"}, <-calls)
+
+	var check checkResponse
+	for i := 0; i < 100; i++ {
+		check = getCheck(t, ts.URL+"/checks/1")
+		if check.State != "running" {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, "done", check.State)
+	assert.Equal(t, checkProgress{Done: 2, Queued: 2}, check.Progress)
+	assert.Equal(t, 2, check.Summary.Total)
+	assert.Equal(t, 1, check.Summary.NotScanned)
+	assert.Len(t, check.Statuses, 1)
+	assert.Len(t, getCheck(t, ts.URL+"/checks/1?all=true").Statuses, 2)
+}
+
+func TestServeErrors(t *testing.T) {
+	ts, _ := testServer(t)
+	defer ts.Close()
+
+	resp, err := http.Post(ts.URL+"/checks", "application/json", strings.NewReader(`{"type":"release","target":"x"}`))
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, err = http.Post(ts.URL+"/checks", "application/json", strings.NewReader(`{"type":"repo","target":"generic-local","deep":true}`))
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, err = http.Get(ts.URL + "/checks/42")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestServeBusy(t *testing.T) {
+	release := make(chan bool)
+	s := newServer(&config.ServerDetails{}, &checkOptions{workers: 1, out: ioutil.Discard, report: newCheckReport()}, 1, time.Hour)
+	s.run = func(args []string, opts *checkOptions) error {
+		<-release
+		return nil
+	}
+	ts := httptest.NewServer(s.handler())
+	defer ts.Close()
+
+	post := func() int {
+		resp, err := http.Post(ts.URL+"/checks", "application/json", strings.NewReader(`{"type":"repo","target":"generic-local"}`))
+		assert.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	assert.Equal(t, http.StatusAccepted, post())
+	assert.Equal(t, http.StatusTooManyRequests, post())
+	release <- true
+	for i := 0; i < 100 && getCheck(t, ts.URL+"/checks/1").State == "running"; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, http.StatusAccepted, post())
+	close(release)
+}
+
+func TestServePrune(t *testing.T) {
+	s := newServer(&config.ServerDetails{}, &checkOptions{}, 1, time.Hour)
+	s.checks["1"] = &serveCheck{ID: "1", State: "done", Finished: time.Now().Add(-2 * time.Hour)}
+	s.checks["2"] = &serveCheck{ID: "2", State: "done", Finished: time.Now()}
+	s.checks["3"] = &serveCheck{ID: "3", State: "running"}
+	s.prune()
+	assert.Len(t, s.checks, 2)
+	assert.NotContains(t, s.checks, "1")
+}
+
+func TestServeRepos(t *testing.T) {
+	ts, _ := testServer(t)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/repos")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	var repos []IndexedRepo
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&repos))
+	assert.Equal(t, []IndexedRepo{{Name: "generic-local", PkgType: "generic", Type: "local"}}, repos)
+}
@@ -0,0 +1,108 @@
+package commands
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+//timePoint a value and when it was polled
+type timePoint struct {
+	Time  time.Time
+	Value float64
+}
+
+//seriesBuffer ring buffer of the points of one series over the last window, safe for concurrent use
+type seriesBuffer struct {
+	mu     sync.RWMutex
+	window time.Duration
+	points []timePoint
+	start  int //oldest point
+	size   int
+}
+
+//newSeriesBuffer room for a window of points polled every interval
+func newSeriesBuffer(window, interval time.Duration) *seriesBuffer {
+	capacity := 2
+	if interval > 0 {
+		capacity += int(window / interval)
+	}
+	return &seriesBuffer{window: window, points: make([]timePoint, capacity)}
+}
+
+//add a point, overwriting the oldest one when the buffer is full. Points that are out of the window
+//are dropped too, so a poll that took longer than the interval does not stretch the history
+func (b *seriesBuffer) add(t time.Time, value float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.size > 0 && t.Before(b.points[(b.start+b.size-1)%len(b.points)].Time) {
+		//out of order, e.g. a replay that went back in time, start over
+		b.start, b.size = 0, 0
+	}
+	if b.size == len(b.points) {
+		b.start = (b.start + 1) % len(b.points)
+		b.size--
+	}
+	b.points[(b.start+b.size)%len(b.points)] = timePoint{Time: t, Value: value}
+	b.size++
+	for b.size > 1 && t.Sub(b.points[b.start].Time) > b.window {
+		b.start = (b.start + 1) % len(b.points)
+		b.size--
+	}
+}
+
+//snapshot the points oldest first
+func (b *seriesBuffer) snapshot() []timePoint {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	points := make([]timePoint, b.size)
+	for i := range points {
+		points[i] = b.points[(b.start+i)%len(b.points)]
+	}
+	return points
+}
+
+//last the newest point, false when there is none
+func (b *seriesBuffer) last() (timePoint, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.size == 0 {
+		return timePoint{}, false
+	}
+	return b.points[(b.start+b.size-1)%len(b.points)], true
+}
+
+//resample the window ending at now into columns of equal duration, oldest first. A column holds the newest
+//point polled before its end, columns before the first point are 0
+func (b *seriesBuffer) resample(now time.Time, columns int) []float64 {
+	if columns < 2 {
+		columns = 2
+	}
+	points := b.snapshot()
+	values := make([]float64, columns)
+	step := b.window / time.Duration(columns)
+	from := now.Add(-b.window)
+	next := 0
+	var value float64
+	for i := range values {
+		end := from.Add(step * time.Duration(i+1))
+		for next < len(points) && !points[next].Time.After(end) {
+			value = points[next].Value
+			next++
+		}
+		values[i] = value
+	}
+	return values
+}
+
+//formatWindow 5m instead of 5m0s
+func formatWindow(window time.Duration) string {
+	text := window.String()
+	if strings.HasSuffix(text, "m0s") {
+		text = strings.TrimSuffix(text, "0s")
+	}
+	if strings.HasSuffix(text, "h0m") {
+		text = strings.TrimSuffix(text, "0m")
+	}
+	return text
+}
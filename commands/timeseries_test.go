@@ -0,0 +1,141 @@
+package commands
+
+import (
+	"image"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	ui "github.com/gizak/termui/v3"
+	helpers "github.com/lorenyeung/indexcheck/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeriesBufferWindow(t *testing.T) {
+	start := time.Date(2021, 3, 4, 10, 0, 0, 0, time.UTC)
+	buffer := newSeriesBuffer(time.Minute, 7*time.Second)
+	for i := 0; i < 20; i++ {
+		buffer.add(start.Add(time.Duration(i*7)*time.Second), float64(i))
+	}
+	points := buffer.snapshot()
+	//133s is the newest point, 73s the oldest still in the window
+	assert.Equal(t, float64(11), points[0].Value)
+	assert.Equal(t, float64(19), points[len(points)-1].Value)
+	assert.Len(t, points, 9)
+
+	//a slow poll drops what fell out of the window, not just the oldest point
+	buffer.add(start.Add(200*time.Second), 20)
+	assert.Len(t, buffer.snapshot(), 1)
+
+	//a replay going back in time starts over
+	buffer.add(start, 1)
+	assert.Equal(t, []timePoint{{Time: start, Value: 1}}, buffer.snapshot())
+}
+
+func TestSeriesBufferResample(t *testing.T) {
+	now := time.Date(2021, 3, 4, 10, 0, 0, 0, time.UTC)
+	buffer := newSeriesBuffer(time.Minute, 10*time.Second)
+	buffer.add(now.Add(-35*time.Second), 1)
+	buffer.add(now.Add(-25*time.Second), 2)
+	buffer.add(now.Add(-5*time.Second), 3)
+
+	//a column per 10s, each holding the newest point before its end
+	assert.Equal(t, []float64{0, 0, 1, 2, 2, 3}, buffer.resample(now, 6))
+	assert.Len(t, buffer.resample(now, 0), 2)
+}
+
+func TestSeriesBufferConcurrent(t *testing.T) {
+	buffer := newSeriesBuffer(time.Minute, time.Second)
+	start := time.Now()
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			buffer.add(start.Add(time.Duration(i)*time.Millisecond), float64(i))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			buffer.resample(start.Add(time.Second), 40)
+		}
+	}()
+	wg.Wait()
+	assert.Len(t, buffer.snapshot(), 62)
+}
+
+func TestPlotPanel(t *testing.T) {
+	root, err := parseLayout([]byte("panel: {type: plot, title: Disk, series: [{expr: app_disk_free_bytes}, {expr: queue_messages_total}]}"))
+	assert.NoError(t, err)
+	plot := newDashboard(root, 5*time.Minute).panels[0].(*plotPanel)
+	assert.Equal(t, "Disk, last 5m", plot.Title)
+
+	frame := newDashboardFrame(testData())
+	frame.Interval = 1
+	plot.update(frame)
+	plot.SetRect(0, 0, 47, 10)
+	plot.Draw(ui.NewBuffer(plot.GetRect()))
+	assert.Len(t, plot.Data, 4)
+	assert.Len(t, plot.Data[0], 40)
+	assert.Equal(t, float64(50), plot.Data[0][39])
+	assert.Equal(t, "1h", formatWindow(time.Hour))
+
+	//points are stamped with when the metrics were taken, the queues are gone for longer than the window
+	taken := frame.Taken
+	frame = newDashboardFrame(testData()[:2])
+	frame.Interval = 1
+	frame.Taken = taken.Add(5*time.Minute + time.Second)
+	plot.update(frame)
+	assert.Equal(t, []string{"app_disk_free_bytes"}, plot.order)
+	assert.Len(t, plot.lines, 1)
+	point, _ := plot.lines["app_disk_free_bytes"].last()
+	assert.Equal(t, frame.Taken, point.Time)
+
+	//a replay that went back in time starts the lines over
+	frame = newDashboardFrame(testData())
+	frame.Interval = 1
+	frame.Taken = taken
+	plot.update(frame)
+	assert.Len(t, plot.order, 4)
+	assert.Len(t, plot.lines["app_disk_free_bytes"].snapshot(), 1)
+}
+
+func TestRemoteConnectionsLegend(t *testing.T) {
+	root, err := parseLayout([]byte(`
+panel:
+  type: plot
+  series:
+    - {expr: 'sum by (pool) (jfrt_http_connections_leased_total)', label: leased}
+    - {expr: jfrt_http_connections_pending_total, label: pending}
+`))
+	assert.NoError(t, err)
+	plot := newDashboard(root, time.Minute).panels[0].(*plotPanel)
+	frame := newDashboardFrame([]helpers.Data{
+		{Name: "jfrt_http_connections_leased_total", Metric: []helpers.Metrics{{Value: "3", Labels: map[string]string{"pool": "npm-remote", "max": "50"}}}},
+		{Name: "jfrt_http_connections_pending_total", Metric: []helpers.Metrics{{Value: "1", Labels: map[string]string{"pool": "npm-remote", "max": "50"}}}},
+		{Name: "docker_remote_jfrt_http_connections_leased_total", Metric: []helpers.Metrics{{Value: "7"}}},
+		{Name: "docker_remote_jfrt_http_connections_pending_total", Metric: []helpers.Metrics{{Value: "0", Labels: map[string]string{"max": "50"}}}},
+	})
+	frame.Interval = 1
+	plot.update(frame)
+	assert.Equal(t, []string{`leased{pool="docker_remote"}`, `leased{pool="npm-remote"}`, `pending{max="50",pool="docker_remote"}`, `pending{max="50",pool="npm-remote"}`}, plot.order)
+	//the legend leaves out max, it is the same for every pool
+	assert.Equal(t, `pending{pool="npm-remote"}`, plot.names[`pending{max="50",pool="npm-remote"}`])
+	//the series of one expression get different colors
+	assert.NotEqual(t, plot.colors[`leased{pool="docker_remote"}`], plot.colors[`leased{pool="npm-remote"}`])
+
+	plot.SetRect(0, 0, 80, 6)
+	buf := ui.NewBuffer(plot.GetRect())
+	plot.Draw(buf)
+	legend := func(row int) string {
+		var text string
+		for x := 1; x < 79; x++ {
+			text += string(buf.GetCell(image.Pt(x, row)).Rune)
+		}
+		return text
+	}
+	assert.True(t, strings.HasSuffix(legend(1), ` - leased{pool="docker_remote"} 7`), legend(1))
+	assert.True(t, strings.HasSuffix(legend(2), " +3 more"), legend(2))
+}
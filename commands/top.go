@@ -0,0 +1,184 @@
+package commands
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	ui "github.com/gizak/termui/v3"
+	"github.com/gizak/termui/v3/widgets"
+	"github.com/jfrog/jfrog-cli-core/v2/utils/config"
+	helpers "github.com/lorenyeung/indexcheck/utils"
+)
+
+//dataRows the series of a snapshot that pass the filter, histograms and summaries as their _sum and _count
+func dataRows(data []helpers.Data, filter *metricsFilter) []metricRow {
+	var rows []metricRow
+	for _, family := range data {
+		if !filter.matchesFamily(family.Name, family.Type) {
+			continue
+		}
+		for _, metric := range family.Metric {
+			if !filter.matchesLabels(metric.Labels) {
+				continue
+			}
+			if metric.Buckets != nil || metric.Quantiles != nil {
+				rows = append(rows, metricRow{Name: family.Name + "_sum", Labels: metric.Labels, Value: metric.Sum, Counter: true})
+				rows = append(rows, metricRow{Name: family.Name + "_count", Labels: metric.Labels, Value: metric.Count, Counter: true})
+				continue
+			}
+			rows = append(rows, metricRow{Name: family.Name, Labels: metric.Labels, Value: metric.Value, Counter: family.Type == "COUNTER"})
+		}
+	}
+	return rows
+}
+
+//relativeChange change as a fraction of the previous value, infinite when the series started from 0
+func relativeChange(d metricDelta) float64 {
+	if d.Before == 0 {
+		if d.Delta == 0 {
+			return 0
+		}
+		if d.Delta < 0 {
+			return math.Inf(-1)
+		}
+		return math.Inf(1)
+	}
+	return d.Delta / math.Abs(d.Before)
+}
+
+//sortTop by absolute change, or by relative change with relative
+func sortTop(deltas []metricDelta, relative bool) {
+	if !relative {
+		sort.SliceStable(deltas, func(i, j int) bool { return math.Abs(deltas[i].Delta) > math.Abs(deltas[j].Delta) })
+		return
+	}
+	sort.SliceStable(deltas, func(i, j int) bool {
+		return math.Abs(relativeChange(deltas[i])) > math.Abs(relativeChange(deltas[j]))
+	})
+}
+
+//topRows table rows of the series that changed, header first
+func topRows(deltas []metricDelta, max int) [][]string {
+	rows := [][]string{{"SERIES", "VALUE", "DELTA", "RATE", "CHANGE"}}
+	for _, d := range deltas {
+		if len(rows) > max {
+			break
+		}
+		if d.Delta == 0 {
+			continue
+		}
+		series := d.Name
+		if len(d.Labels) > 0 {
+			series += "{" + formatLabels(d.Labels) + "}"
+		}
+		change := "new"
+		if relative := relativeChange(d); !math.IsInf(relative, 0) {
+			change = fmt.Sprintf("%+.1f%%", relative*100)
+		}
+		delta := humanizeValue(d.Name, strconv.FormatFloat(d.Delta, 'f', -1, 64))
+		if d.Reset {
+			delta += " (reset)"
+		}
+		rows = append(rows, []string{series, humanizeValue(d.Name, strconv.FormatFloat(d.After, 'f', -1, 64)), delta, fmt.Sprintf("%.2f/s", d.Rate), change})
+	}
+	return rows
+}
+
+//metricsTop poll the metrics on the interval and rank the series that changed the most since the last poll
+func metricsTop(config *config.ServerDetails, conf *MetricsConfiguration, filter *metricsFilter, interval time.Duration) error {
+	intervalSeconds := int(interval.Seconds())
+	if intervalSeconds < 1 {
+		intervalSeconds = 1
+	}
+	data, _, offset, err := helpers.GetMetricsData(config, conf.product, 0, false, intervalSeconds)
+	if err != nil {
+		return err
+	}
+	previous, previousTime := dataRows(data, filter), time.Now()
+
+	if err := ui.Init(); err != nil {
+		fmt.Printf("failed to initialize termui: %v", err)
+		return err
+	}
+	defer ui.Close()
+
+	header := widgets.NewParagraph()
+	header.Title = "Metrics top"
+	table := widgets.NewTable()
+	table.RowSeparator = false
+	table.TextStyle = ui.NewStyle(ui.ColorWhite)
+	table.RowStyles[0] = ui.NewStyle(ui.ColorYellow, ui.ColorClear, ui.ModifierBold)
+	table.ColumnResizer = func() {
+		//every column but the series fits a humanized number
+		width := table.Inner.Dx() - 4*14
+		if width < 20 {
+			width = 20
+		}
+		table.ColumnWidths = []int{width, 14, 14, 14, 14}
+	}
+	resize := func() {
+		width, height := ui.TerminalDimensions()
+		header.SetRect(0, 0, width, 4)
+		table.SetRect(0, 4, width, height)
+	}
+	resize()
+
+	relative := false
+	var deltas []metricDelta
+	status := "waiting " + interval.String() + " for the first changes"
+	render := func() {
+		sortTop(deltas, relative)
+		sortBy := "absolute"
+		if relative {
+			sortBy = "relative"
+		}
+		header.Text = status + "\nSorted by " + sortBy + " change, every " + interval.String() + " on " + config.ServerId + ". a: absolute, r: relative, s: switch, q: quit"
+		table.Rows = topRows(deltas, table.Inner.Dy()-1)
+		if len(table.Rows) == 1 {
+			table.Rows = append(table.Rows, []string{"no changes", "", "", "", ""})
+		}
+		ui.Render(header, table)
+	}
+	render()
+
+	uiEvents := ui.PollEvents()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case e := <-uiEvents:
+			switch e.ID {
+			case "q", "<C-c>":
+				return nil
+			case "a":
+				relative = false
+			case "r":
+				relative = true
+			case "s":
+				relative = !relative
+			case "<Resize>":
+				resize()
+				ui.Clear()
+			}
+			render()
+		case <-ticker.C:
+			var lastUpdate string
+			data, lastUpdate, offset, err = helpers.GetMetricsData(config, conf.product, offset, false, intervalSeconds)
+			switch {
+			case err != nil:
+				status = "Failed to get metrics: " + err.Error()
+			case len(data) == 0:
+				status = "No new metrics since " + lastUpdate
+			default:
+				current := dataRows(data, filter)
+				deltas = diffSnapshots(previous, current, time.Since(previousTime))
+				previous, previousTime = current, time.Now()
+				status = "Last updated: " + lastUpdate + ", " + strconv.Itoa(len(current)) + " series"
+			}
+			render()
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package commands
+
+import (
+	"testing"
+	"time"
+
+	helpers "github.com/lorenyeung/indexcheck/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricsTop(t *testing.T) {
+	filter, err := newMetricsFilter("", "", "")
+	assert.NoError(t, err)
+	previous := dataRows(testData(), filter)
+	//the summary is split into _sum and _count
+	assert.Len(t, previous, 7)
+
+	current := dataRows([]helpers.Data{
+		{Name: "queue_messages_total", Type: "GAUGE", Metric: []helpers.Metrics{
+			{Value: "12100", Labels: map[string]string{"queue_name": "Index"}},
+			{Value: "30", Labels: map[string]string{"queue_name": "IndexRetry"}},
+			{Value: "40", Labels: map[string]string{"queue_name": "Persist"}},
+		}},
+	}, filter)
+	deltas := diffSnapshots(previous, current, 10*time.Second)
+
+	rows := topRows(deltas, 10)
+	assert.Len(t, rows, 3)
+	assert.Equal(t, `queue_messages_total{queue_name="Index"}`, rows[1][0])
+	assert.Equal(t, "+0.8%", rows[1][4])
+
+	sortTop(deltas, true)
+	rows = topRows(deltas, 10)
+	assert.Equal(t, `queue_messages_total{queue_name="IndexRetry"}`, rows[1][0])
+	assert.Equal(t, "+900.0%", rows[1][4])
+	assert.Equal(t, "10.00/s", rows[2][3])
+
+	assert.Len(t, topRows(deltas, 1), 2)
+
+	filter, err = newMetricsFilter("", "", "queue_name=Persist")
+	assert.NoError(t, err)
+	assert.Len(t, dataRows(testData(), filter), 1)
+}
@@ -0,0 +1,123 @@
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/jfrog/jfrog-cli-core/v2/utils/config"
+	"github.com/jfrog/jfrog-client-go/utils/log"
+)
+
+//watchChanges status transitions between two runs of the same checks
+type watchChanges struct {
+	NowScanned   []artifactStatus
+	NewUnscanned []artifactStatus
+	NewFailures  []artifactStatus
+}
+
+func (w watchChanges) empty() bool {
+	return len(w.NowScanned) == 0 && len(w.NewUnscanned) == 0 && len(w.NewFailures) == 0
+}
+
+//diffStatuses compare two sorted runs, an artifact only counts once, failures take precedence over unscanned
+func diffStatuses(previous, current []artifactStatus) watchChanges {
+	var changes watchChanges
+	previousMap := make(map[string]artifactStatus, len(previous))
+	for _, status := range previous {
+		previousMap[status.key()] = status
+	}
+	for _, status := range current {
+		before, seen := previousMap[status.key()]
+		switch {
+		case status.Scanned:
+			if seen && !before.Scanned {
+				changes.NowScanned = append(changes.NowScanned, status)
+			}
+		case status.isFailure():
+			if !seen || !before.isFailure() {
+				changes.NewFailures = append(changes.NewFailures, status)
+			}
+		default:
+			if !seen || before.Scanned {
+				changes.NewUnscanned = append(changes.NewUnscanned, status)
+			}
+		}
+	}
+	return changes
+}
+
+//watchChecks run the checks once as normal, then re-run them on the interval and only print what changed
+func watchChecks(args []string, interval time.Duration, config *config.ServerDetails, opts *checkOptions) error {
+	fmt.Println("Watching every", interval, "press Ctrl+C to stop")
+	err := runChecks(args, config, opts)
+	if err != nil {
+		return err
+	}
+	previous := opts.report.Statuses()
+	total, startNotIndex := opts.report.Totals()
+	lastNotIndex := startNotIndex
+	watchStart := time.Now()
+	fmt.Println(time.Now().Format("2006.01.02 15:04:05"), "unscanned:", startNotIndex, "/", total)
+	breached := notifyThreshold(false, args, config, watchStart, opts)
+
+	//later runs only report changes, and should not reindex the same artifacts every interval
+	quiet := opts.rerun(ioutil.Discard)
+	quiet.reindex = false
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		run := quiet.rerun(ioutil.Discard)
+		err := runChecks(args, config, run)
+		if err != nil {
+			log.Warn("Watch run failed:", err)
+			continue
+		}
+		current := run.report.Statuses()
+		printWatchChanges(diffStatuses(previous, current))
+
+		total, notIndexCount := run.report.Totals()
+		drained := startNotIndex - notIndexCount
+		rate := float64(drained) / time.Since(watchStart).Minutes()
+		fmt.Println(time.Now().Format("2006.01.02 15:04:05"), "unscanned:", notIndexCount, "/", total, " change:", notIndexCount-lastNotIndex, " drained since start:", drained, fmt.Sprintf("(%.1f/min)", rate))
+		breached = notifyThreshold(breached, args, config, watchStart, run)
+		previous = current
+		lastNotIndex = notIndexCount
+	}
+	return nil
+}
+
+//notifyThreshold notify once when the unscanned count reaches the threshold, returns whether it is breached
+func notifyThreshold(breached bool, args []string, config *config.ServerDetails, watchStart time.Time, opts *checkOptions) bool {
+	if opts.notify == nil || opts.notifyThreshold <= 0 {
+		return false
+	}
+	_, notIndexCount := opts.report.Totals()
+	if notIndexCount < opts.notifyThreshold {
+		return false
+	}
+	if !breached {
+		summary := newNotifySummary("threshold", args, config.Url, time.Since(watchStart), opts.report)
+		summary.Threshold = opts.notifyThreshold
+		err := opts.notify.send(summary)
+		if err != nil {
+			log.Error("Failed to send notification:", err)
+		}
+	}
+	return true
+}
+
+func printWatchChanges(changes watchChanges) {
+	if changes.empty() {
+		return
+	}
+	for _, status := range changes.NowScanned {
+		fmt.Println(fmt.Sprintf("%-19v", "now scanned"), "\t", status.key())
+	}
+	for _, status := range changes.NewUnscanned {
+		fmt.Println(fmt.Sprintf("%-19v", "new "+status.Status), "\t", status.key())
+	}
+	for _, status := range changes.NewFailures {
+		fmt.Println(fmt.Sprintf("%-19v", "new "+status.Status), "\t", status.key())
+	}
+}
@@ -0,0 +1,29 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffStatuses(t *testing.T) {
+	previous := []artifactStatus{
+		{Repo: "generic-local", Path: "/a.tar.gz", Status: "not scanned"},
+		{Repo: "generic-local", Path: "/b.tar.gz", Status: "scanned", Scanned: true},
+		{Repo: "generic-local", Path: "/c.tar.gz", Status: "in progress"},
+		{Repo: "generic-local", Path: "/d.tar.gz", Status: "failed"},
+	}
+	current := []artifactStatus{
+		{Repo: "generic-local", Path: "/a.tar.gz", Status: "scanned", Scanned: true},
+		{Repo: "generic-local", Path: "/b.tar.gz", Status: "scanned", Scanned: true},
+		{Repo: "generic-local", Path: "/c.tar.gz", Status: "failed"},
+		{Repo: "generic-local", Path: "/d.tar.gz", Status: "failed"},
+		{Repo: "generic-local", Path: "/e.tar.gz", Status: "not scanned"},
+	}
+	changes := diffStatuses(previous, current)
+	assert.Equal(t, []artifactStatus{current[0]}, changes.NowScanned)
+	assert.Equal(t, []artifactStatus{current[2]}, changes.NewFailures)
+	assert.Equal(t, []artifactStatus{current[4]}, changes.NewUnscanned)
+
+	assert.True(t, diffStatuses(current, current).empty())
+}
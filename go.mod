@@ -12,4 +12,5 @@ require (
 	github.com/prometheus/prom2json v1.3.0
 	github.com/sirupsen/logrus v1.8.1
 	github.com/stretchr/testify v1.7.0
+	gopkg.in/yaml.v2 v2.4.0
 )
@@ -27,5 +27,7 @@ func getCommands() []components.Command {
 		commands.GetGraphCommand(),
 		commands.GetMetricsCommand(),
 		commands.GetCheckCommand(),
+		commands.GetExportCommand(),
+		commands.GetServeCommand(),
 	}
 }
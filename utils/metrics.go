@@ -0,0 +1,114 @@
+package helpers
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/common/expfmt"
+	"github.com/prometheus/prom2json"
+)
+
+//ErrNoMetrics the server answered with an empty body, the only case that is treated as stale data
+var ErrNoMetrics = errors.New("received no metrics")
+
+//MetricsParseError a scrape that is not valid Prometheus text, Line is the line of the original response
+type MetricsParseError struct {
+	Product string
+	Line    int
+	Text    string
+	Msg     string
+}
+
+func (e *MetricsParseError) Error() string {
+	text := e.Text
+	if len(text) > 120 {
+		text = text[:120] + "..."
+	}
+	return "Failed to parse " + e.Product + " metrics on line " + strconv.Itoa(e.Line) + ": " + e.Msg + ": " + strconv.Quote(text)
+}
+
+var metricNameRegex = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+
+//isSampleLine whether a line looks like name{labels} value [timestamp]
+func isSampleLine(line string) bool {
+	end := strings.IndexAny(line, "{ \t")
+	if end <= 0 || !metricNameRegex.MatchString(line[:end]) {
+		return false
+	}
+	rest := line[end:]
+	if rest[0] == '{' {
+		closing := strings.LastIndex(rest, "}")
+		if closing < 0 {
+			return false
+		}
+		rest = rest[closing+1:]
+	}
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return false
+	}
+	_, err := strconv.ParseFloat(fields[0], 64)
+	return err == nil
+}
+
+//sanitizeMetrics work around Xray's known format quirks: help text with unescaped new lines is joined back
+//onto its HELP line, and a missing trailing new line is added. Returns the original line number of every line
+func sanitizeMetrics(metrics []byte) (string, []int) {
+	lines := strings.Split(strings.TrimRight(string(metrics), "\n"), "\n")
+	var sanitized []string
+	var original []int
+	inHelp := false
+	for i, line := range lines {
+		line = strings.TrimSuffix(line, "\r")
+		trimmed := strings.TrimSpace(line)
+		if inHelp && trimmed != "" && !strings.HasPrefix(trimmed, "#") && !isSampleLine(trimmed) {
+			sanitized[len(sanitized)-1] += `\n` + strings.ReplaceAll(line, `\`, `\\`)
+			continue
+		}
+		inHelp = strings.HasPrefix(trimmed, "# HELP ")
+		sanitized = append(sanitized, line)
+		original = append(original, i+1)
+	}
+	return strings.Join(sanitized, "\n") + "\n", original
+}
+
+//ParseMetrics parse Prometheus text into families sorted by name
+func ParseMetrics(metrics []byte) ([]*prom2json.Family, error) {
+	if len(bytes.TrimSpace(metrics)) == 0 {
+		return nil, ErrNoMetrics
+	}
+	text, original := sanitizeMetrics(metrics)
+	var parser expfmt.TextParser
+	metricFamilies, err := parser.TextToMetricFamilies(strings.NewReader(text))
+	if err != nil {
+		var parseErr expfmt.ParseError
+		if !errors.As(err, &parseErr) {
+			return nil, err
+		}
+		line := parseErr.Line
+		if line > 0 && line <= len(original) {
+			line = original[line-1]
+		}
+		lines := strings.Split(string(metrics), "\n")
+		var lineText string
+		if line > 0 && line <= len(lines) {
+			lineText = lines[line-1]
+		}
+		return nil, &MetricsParseError{Line: line, Text: lineText, Msg: parseErr.Msg}
+	}
+
+	names := make([]string, 0, len(metricFamilies))
+	for name := range metricFamilies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	result := make([]*prom2json.Family, 0, len(names))
+	for _, name := range names {
+		result = append(result, prom2json.NewFamily(metricFamilies[name]))
+	}
+	return result, nil
+}
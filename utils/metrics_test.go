@@ -0,0 +1,42 @@
+package helpers
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/prometheus/prom2json"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMetricsQuirks(t *testing.T) {
+	//no trailing new line, and help text with an unescaped new line
+	families, err := ParseMetrics([]byte("# HELP queue_messages_total The number of messages\nin the queue\n# TYPE queue_messages_total gauge\n" +
+		"queue_messages_total{queue_name=\"Index\"} 5\n# HELP sys_load_1 Host load average\n# TYPE sys_load_1 gauge\nsys_load_1 1.5"))
+	assert.NoError(t, err)
+	assert.Len(t, families, 2)
+	assert.Equal(t, "queue_messages_total", families[0].Name)
+	assert.Equal(t, "The number of messages\nin the queue", families[0].Help)
+	assert.Equal(t, "5", families[0].Metrics[0].(prom2json.Metric).Value)
+	assert.Equal(t, "1.5", families[1].Metrics[0].(prom2json.Metric).Value)
+}
+
+func TestParseMetricsErrors(t *testing.T) {
+	_, err := ParseMetrics([]byte("  \n"))
+	assert.True(t, errors.Is(err, ErrNoMetrics))
+
+	_, err = ParseMetrics([]byte("# HELP sys_load_1 Host load\naverage\n# TYPE sys_load_1 gauge\nsys_load_1 1.5\nsys_load_5 high\n"))
+	var parseErr *MetricsParseError
+	assert.True(t, errors.As(err, &parseErr))
+	//the line of the original response, before the help text was joined
+	assert.Equal(t, 5, parseErr.Line)
+	assert.Equal(t, "sys_load_5 high", parseErr.Text)
+	parseErr.Product = "xray"
+	assert.Contains(t, parseErr.Error(), "Failed to parse xray metrics on line 5")
+}
+
+func TestIsSampleLine(t *testing.T) {
+	assert.True(t, isSampleLine(`queue_messages_total{queue_name="Index"} 5`))
+	assert.True(t, isSampleLine(`sys_load_1 NaN 1638862071581`))
+	assert.False(t, isSampleLine(`in the queue`))
+	assert.False(t, isSampleLine(`metrics {are} fun`))
+}
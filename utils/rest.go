@@ -9,6 +9,7 @@ import (
 	"io/ioutil"
 	"mime/multipart"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -20,7 +21,6 @@ import (
 	"github.com/jfrog/jfrog-client-go/utils"
 	"github.com/jfrog/jfrog-client-go/utils/errorutils"
 
-	dto "github.com/prometheus/client_model/go"
 	"github.com/prometheus/prom2json"
 
 	"github.com/jfrog/jfrog-client-go/utils/log"
@@ -49,31 +49,46 @@ type Data struct {
 }
 
 type FileInfo struct {
-	Size     string          `json:"size"`
-	MimeType string          `json:"mimeType"`
-	Children []FileInfoChild `json:"children"`
+	Size      string            `json:"size"`
+	MimeType  string            `json:"mimeType"`
+	Children  []FileInfoChild   `json:"children"`
+	Checksums FileInfoChecksums `json:"checksums"`
+}
+
+type FileInfoChecksums struct {
+	Sha1   string `json:"sha1"`
+	Sha256 string `json:"sha256"`
 }
 
 type FileInfoChild struct {
 	Uri string `json:"uri"`
 }
 
-//Metrics struct
+//Metrics struct, Value for counters and gauges, Buckets or Quantiles with Sum and Count for histograms and summaries
 type Metrics struct {
-	TimestampMs string       `json:"timestamp_ms"`
-	Value       string       `json:"value"`
-	Labels      LabelsStruct `json:"labels,omitempty"`
+	TimestampMs string            `json:"timestamp_ms"`
+	Value       string            `json:"value"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Buckets     map[string]string `json:"buckets,omitempty"`
+	Quantiles   map[string]string `json:"quantiles,omitempty"`
+	Sum         string            `json:"sum,omitempty"`
+	Count       string            `json:"count,omitempty"`
 }
 
-//LabelsStruct struct
-type LabelsStruct struct {
-	Start     string `json:"start"`
-	End       string `json:"end"`
-	Status    string `json:"status"`
-	Type      string `json:"type"`
-	Max       string `json:"max"`
-	Pool      string `json:"pool"`
-	QueueName string `json:"queue_name"`
+//Scalar value of a counter or gauge, the mean of a histogram or summary
+func (m Metrics) Scalar() string {
+	if m.Value != "" || m.Count == "" {
+		return m.Value
+	}
+	sum, err := strconv.ParseFloat(m.Sum, 64)
+	if err != nil {
+		return ""
+	}
+	count, err := strconv.ParseFloat(m.Count, 64)
+	if err != nil || count == 0 {
+		return "0"
+	}
+	return strconv.FormatFloat(sum/count, 'f', -1, 64)
 }
 
 type SupportedTypes struct {
@@ -114,8 +129,8 @@ func GetSupportedTypesJSON() (SupportedTypes, error) {
 	return supportTypesFile, nil
 }
 
-//GetConfig get config from cli
-func GetConfig() (*config.ServerDetails, error) {
+//GetConfig get config from cli, checking that the given products are up, MetricsBoth for both
+func GetConfig(products ...string) (*config.ServerDetails, error) {
 	//TODO handle custom server id input
 	serversIds, serverIDDefault, _ := GetServersIdAndDefault()
 	if len(serversIds) == 0 {
@@ -130,20 +145,64 @@ func GetConfig() (*config.ServerDetails, error) {
 		//TODO print some error and exit
 	}
 
-	ping, respCode, _ := GetRestAPI("GET", true, config.Url+"xray/api/v1/system/ping", config, "", nil, 1)
-	if respCode != 200 {
-		return nil, errors.New("Xray is not up:" + string(ping))
+	err = pingProducts(config, products)
+	if err != nil {
+		return nil, err
 	}
-
 	return config, nil
 }
 
-func GetMetricsDataRaw(config *config.ServerDetails) ([]byte, error) {
-	metrics, respCode, _ := GetRestAPI("GET", true, config.Url+"xray/api/v1/metrics", config, "", nil, 1)
+//pingProducts only the products that are queried need to be up
+func pingProducts(config *config.ServerDetails, products []string) error {
+	for _, product := range products {
+		switch product {
+		case MetricsXray:
+			ping, respCode, _ := GetRestAPI("GET", true, config.Url+"xray/api/v1/system/ping", config, "", nil, 1)
+			if respCode != 200 {
+				return errors.New("Xray is not up:" + string(ping))
+			}
+		case MetricsArtifactory:
+			ping, respCode, _ := GetRestAPI("GET", true, config.Url+"artifactory/api/system/ping", config, "", nil, 1)
+			if respCode != 200 {
+				return errors.New("Artifactory is not up:" + string(ping))
+			}
+		case MetricsBoth:
+			//like GetMetricsFamilies, one of them is enough
+			xrayErr := pingProducts(config, []string{MetricsXray})
+			artifactoryErr := pingProducts(config, []string{MetricsArtifactory})
+			if xrayErr != nil && artifactoryErr != nil {
+				return errors.New(xrayErr.Error() + ", " + artifactoryErr.Error())
+			}
+			if xrayErr != nil {
+				log.Warn(xrayErr)
+			}
+			if artifactoryErr != nil {
+				log.Warn(artifactoryErr)
+			}
+		default:
+			return errors.New("Unsupported product:" + product + ", expected xray, artifactory or both")
+		}
+	}
+	return nil
+}
+
+//metrics products, MetricsBoth merges both products with a product label on every metric
+const (
+	MetricsXray        = "xray"
+	MetricsArtifactory = "artifactory"
+	MetricsBoth        = "both"
+)
+
+//GetMetricsDataRaw get the metrics of a single product, xray or artifactory
+func GetMetricsDataRaw(config *config.ServerDetails, product string) ([]byte, error) {
+	if product != MetricsXray && product != MetricsArtifactory {
+		return nil, errors.New("Unsupported metrics product:" + product + ", expected xray or artifactory")
+	}
+	metrics, respCode, _ := GetRestAPI("GET", true, config.Url+product+"/api/v1/metrics", config, "", nil, 1)
 	if respCode != 200 {
-		return nil, errors.New("Received " + strconv.Itoa(respCode) + " HTTP code while getting metrics")
+		return nil, errors.New("Received " + strconv.Itoa(respCode) + " HTTP code while getting " + product + " metrics")
 	}
-	log.Debug("Received ", respCode, " while getting metrics")
+	log.Debug("Received ", respCode, " while getting ", product, " metrics")
 	return metrics, nil
 }
 
@@ -158,40 +217,112 @@ func match(s string) string {
 	return ""
 }
 
-func GetMetricsDataJSON(config *config.ServerDetails, prettyPrint bool) ([]byte, error) {
-	metrics, err := GetMetricsDataRaw(config)
+func GetMetricsDataJSON(config *config.ServerDetails, product string, prettyPrint bool) ([]byte, error) {
+	result, err := GetMetricsFamilies(config, product)
 	if err != nil {
 		return nil, err
 	}
-	mfChan := make(chan *dto.MetricFamily, 1024)
+	return MetricsFamiliesJSON(result, prettyPrint)
+}
 
-	// Missing input means we are reading from an URL. stupid hack because Artifactory is missing a newline return
-	file := string(metrics) + "\n"
+//GetMetricsFamilies get metrics parsed into families. With MetricsBoth a product that fails is skipped as long as the other one works,
+//and a family whose type differs between the products is only kept from Xray
+func GetMetricsFamilies(config *config.ServerDetails, product string) ([]*prom2json.Family, error) {
+	if product != MetricsBoth {
+		metrics, err := GetMetricsDataRaw(config, product)
+		if err != nil {
+			return nil, err
+		}
+		return parseProductMetrics(metrics, product)
+	}
 
-	go func() {
-		if err := prom2json.ParseReader(strings.NewReader(file), mfChan); err != nil {
-			//issue with reading metrics if there is an unescaped new line char
-			log.Warn("error reading metrics:", err)
+	var result []*prom2json.Family
+	var errs []string
+	byName := make(map[string]*prom2json.Family)
+	for _, product := range []string{MetricsXray, MetricsArtifactory} {
+		metrics, err := GetMetricsDataRaw(config, product)
+		if err != nil {
+			log.Warn(err)
+			errs = append(errs, err.Error())
+			continue
+		}
+		families, err := parseProductMetrics(metrics, product)
+		if errors.Is(err, ErrNoMetrics) {
+			continue
+		}
+		if err != nil {
+			log.Warn(err)
+			errs = append(errs, err.Error())
+			continue
+		}
+		for _, family := range families {
+			tagProduct(family, product)
+			if existing, ok := byName[family.Name]; ok {
+				if existing.Type != family.Type {
+					log.Warn("Skipping ", product, " metric ", family.Name, " of type ", family.Type, ", it is ", existing.Type, " in ", MetricsXray)
+					continue
+				}
+				existing.Metrics = append(existing.Metrics, family.Metrics...)
+				continue
+			}
+			byName[family.Name] = family
+			result = append(result, family)
+		}
+	}
+	if len(errs) == 2 {
+		return nil, errors.New(strings.Join(errs, ", "))
+	}
+	if len(result) == 0 {
+		return nil, ErrNoMetrics
+	}
+	return result, nil
+}
 
-			return
+//parseProductMetrics ParseMetrics with the product in parse errors
+func parseProductMetrics(metrics []byte, product string) ([]*prom2json.Family, error) {
+	families, err := ParseMetrics(metrics)
+	var parseErr *MetricsParseError
+	if errors.As(err, &parseErr) {
+		parseErr.Product = product
+	}
+	return families, err
+}
+
+//tagProduct add a product label to every metric of the family
+func tagProduct(family *prom2json.Family, product string) {
+	for i, metric := range family.Metrics {
+		switch m := metric.(type) {
+		case prom2json.Metric:
+			m.Labels = withProduct(m.Labels, product)
+			family.Metrics[i] = m
+		case prom2json.Summary:
+			m.Labels = withProduct(m.Labels, product)
+			family.Metrics[i] = m
+		case prom2json.Histogram:
+			m.Labels = withProduct(m.Labels, product)
+			family.Metrics[i] = m
 		}
-	}()
+	}
+}
 
-	//TODO: Hella inefficient?
-	//fmt.Println("before", time.Now())
-	result := []*prom2json.Family{}
-	for mf := range mfChan {
-		result = append(result, prom2json.NewFamily(mf))
+func withProduct(labels map[string]string, product string) map[string]string {
+	if labels == nil {
+		labels = make(map[string]string)
 	}
+	labels["product"] = product
+	return labels
+}
 
+//MetricsFamiliesJSON marshal families the same way prom2json does
+func MetricsFamiliesJSON(result []*prom2json.Family, prettyPrint bool) ([]byte, error) {
 	var jsonText []byte
+	var err error
 	//pretty print
 	if prettyPrint {
-		jsonText, err := json.MarshalIndent(result, "", "    ")
+		jsonText, err = json.MarshalIndent(result, "", "    ")
 		if err != nil {
 			return nil, errors.New(err.Error() + " at " + string(Trace().Fn) + " on line " + string(strconv.Itoa(Trace().Line)))
 		}
-		fmt.Println(string(jsonText))
 		return jsonText, nil
 	}
 	jsonText, err = json.Marshal(result)
@@ -225,19 +356,21 @@ func ByteCountDecimal(b int64) string {
 	return fmt.Sprintf("%.1f %cB", float64(b)/float64(div), "kMGTPE"[exp])
 }
 
-func GetMetricsData(config *config.ServerDetails, counter int, prettyPrint bool, interval int) ([]Data, string, int, error) {
+func GetMetricsData(config *config.ServerDetails, product string, counter int, prettyPrint bool, interval int) ([]Data, string, int, error) {
 	//log.Info("hello")
 	//TODO check if token vs password apikey
-	jsonText, err := GetMetricsDataJSON(config, prettyPrint)
-	if err != nil {
+	var metricsData []Data
+	jsonText, err := GetMetricsDataJSON(config, product, prettyPrint)
+	if err != nil && !errors.Is(err, ErrNoMetrics) {
 		//no need to show error fn here
 		return nil, "", 0, err
 	}
-
-	var metricsData []Data
-	err = json.Unmarshal(jsonText, &metricsData)
-	if err != nil {
-		return nil, "", 0, errors.New(err.Error() + " at " + string(Trace().Fn) + " on line " + string(strconv.Itoa(Trace().Line)))
+	//an empty response is stale data, anything that fails to parse is an error
+	if err == nil {
+		err = json.Unmarshal(jsonText, &metricsData)
+		if err != nil {
+			return nil, "", 0, errors.New(err.Error() + " at " + string(Trace().Fn) + " on line " + string(strconv.Itoa(Trace().Line)))
+		}
 	}
 
 	currentTime := time.Now()
@@ -294,29 +427,43 @@ func Trace() TraceData {
 	return trace
 }
 
+//artifactStatusRequest body of the Xray artifact scan status API
+type artifactStatusRequest struct {
+	PkgType string `json:"repository_pkg_type"`
+	Path    string `json:"path"`
+	Sha256  string `json:"sha256"`
+}
+
+//buildStatusRequest body of the Xray build scan status API
+type buildStatusRequest struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Project string `json:"project,omitempty"`
+}
+
 //
-func GetStatus(repo, pkgtype, uri, sha256, scanType string, config *config.ServerDetails) (string, bool) {
+func GetStatus(repo, pkgtype, uri, sha256, scanType, project string, config *config.ServerDetails) (string, bool) {
 
 	//there are odd ball cases where there is no Sha256 returned e.g. yum_3.2-25-2_all.deb that need to be considered
 	if sha256 == "" && scanType == "artifact" {
 		return "No sha256 in filelist", false
 	}
-	var body string
+	var request interface{}
 	switch scanType {
 	case "artifact":
-		body = "{\"repository_pkg_type\":" + "\"" + pkgtype + "\"," +
-			"\"path\":" + "\"" + repo + uri + "\"," +
-			"\"sha256\":" + "\"" + sha256 + "\"" +
-			"}"
+		request = artifactStatusRequest{PkgType: pkgtype, Path: repo + uri, Sha256: sha256}
 	case "build":
 		//re-use repo = build name, uri = build number
-		body = "{\"name\":" + "\"" + repo + "\"," +
-			"\"version\":" + "\"" + uri + "\"" +
-			"}"
+		request = buildStatusRequest{Name: repo, Version: uri, Project: project}
 	case "releaseBundle":
 	default:
 		return scanType + " not supported", false
 	}
+	data, err := json.Marshal(request)
+	if err != nil {
+		return "Failed getting details", false
+	}
+	body := string(data)
 
 	headers := map[string]string{"Content-type": "application/json"}
 	resp, respCode, _ := GetRestAPI("POST", true, config.XrayUrl+"api/v1/scan/status/"+scanType, config, body, headers, 0)
@@ -326,7 +473,7 @@ func GetStatus(repo, pkgtype, uri, sha256, scanType string, config *config.Serve
 	}
 
 	var detail detailArtifact
-	err := json.Unmarshal(resp, &detail)
+	err = json.Unmarshal(resp, &detail)
 	if err != nil {
 		fmt.Println("Error unmarshalling details:", err)
 	}
@@ -345,6 +492,80 @@ type IndexedRepo struct {
 	Type    string `json:"type"`
 }
 
+type checksumSearch struct {
+	Results []checksumSearchResult `json:"results"`
+}
+
+type checksumSearchResult struct {
+	Uri string `json:"uri"`
+}
+
+//SearchChecksum get the storage uris of the artifacts with a checksum, sha256 is preferred over sha1
+func SearchChecksum(sha1, sha256 string, config *config.ServerDetails) ([]string, error) {
+	query := "sha1=" + sha1
+	if sha256 != "" {
+		query = "sha256=" + sha256
+	}
+	searchData, respCode, _ := GetRestAPI("GET", true, config.ArtifactoryUrl+"api/search/checksum?"+query, config, "", nil, 0)
+	if respCode != 200 {
+		return nil, errors.New("Checksum search received unexpected response code:" + strconv.Itoa(respCode) + " :" + string(searchData))
+	}
+	var search checksumSearch
+	err := json.Unmarshal(searchData, &search)
+	if err != nil {
+		return nil, errors.New(err.Error() + " at " + string(Trace().Fn) + " on line " + string(strconv.Itoa(Trace().Line)))
+	}
+	var uris []string
+	for i := range search.Results {
+		uris = append(uris, search.Results[i].Uri)
+	}
+	return uris, nil
+}
+
+type projectRepo struct {
+	Key string `json:"key"`
+}
+
+//GetProjectRepos get the repositories that belong to a project
+func GetProjectRepos(project string, config *config.ServerDetails) ([]string, error) {
+	projectRepoData, respCode, _ := GetRestAPI("GET", true, config.ArtifactoryUrl+"api/repositories?project="+url.QueryEscape(project), config, "", nil, 0)
+	if respCode != 200 {
+		return nil, errors.New("Project repository list received unexpected response code:" + strconv.Itoa(respCode) + " :" + string(projectRepoData))
+	}
+	var projectRepos []projectRepo
+	err := json.Unmarshal(projectRepoData, &projectRepos)
+	if err != nil {
+		return nil, errors.New(err.Error() + " at " + string(Trace().Fn) + " on line " + string(strconv.Itoa(Trace().Line)))
+	}
+	var repos []string
+	for i := range projectRepos {
+		repos = append(repos, projectRepos[i].Key)
+	}
+	return repos, nil
+}
+
+//RepoConfig repository configuration, only the fields needed to resolve virtual repositories
+type RepoConfig struct {
+	Key          string   `json:"key"`
+	Rclass       string   `json:"rclass"`
+	PackageType  string   `json:"packageType"`
+	Repositories []string `json:"repositories"`
+}
+
+//GetRepoConfig get repository configuration from Artifactory
+func GetRepoConfig(repo string, config *config.ServerDetails) (RepoConfig, error) {
+	var repoConfig RepoConfig
+	repoConfigData, respCode, _ := GetRestAPI("GET", true, config.ArtifactoryUrl+"api/repositories/"+repo, config, "", nil, 0)
+	if respCode != 200 {
+		return repoConfig, errors.New("Repository configuration received unexpected response code:" + strconv.Itoa(respCode) + " :" + string(repoConfigData))
+	}
+	err := json.Unmarshal(repoConfigData, &repoConfig)
+	if err != nil {
+		return repoConfig, errors.New(err.Error() + " at " + string(Trace().Fn) + " on line " + string(strconv.Itoa(Trace().Line)))
+	}
+	return repoConfig, nil
+}
+
 //Test if remote repository exists and is a remote
 func CheckTypeAndRepoParams(config *config.ServerDetails) ([]IndexedRepo, error) {
 	repoCheckData, repoStatusCode, _ := GetRestAPI("GET", true, config.ArtifactoryUrl+"api/xrayRepo/getIndex", config, "", nil, 1)
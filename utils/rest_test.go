@@ -0,0 +1,151 @@
+package helpers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jfrog/jfrog-cli-core/v2/utils/config"
+	"github.com/prometheus/prom2json"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetMetricsFamiliesBoth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/xray/api/v1/metrics":
+			w.Write([]byte("# HELP sys_load_1 Host load average in the last minute\n# TYPE sys_load_1 gauge\nsys_load_1 1.5\n"))
+		case "/artifactory/api/v1/metrics":
+			w.Write([]byte("# HELP sys_load_1 Host load average in the last minute\n# TYPE sys_load_1 gauge\nsys_load_1 0.5\n" +
+				"# HELP jfrt_runtime_heap_processors_total Available Processors\n# TYPE jfrt_runtime_heap_processors_total counter\njfrt_runtime_heap_processors_total 8\n"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	serverConfig := &config.ServerDetails{Url: server.URL + "/"}
+
+	families, err := GetMetricsFamilies(serverConfig, MetricsXray)
+	assert.NoError(t, err)
+	assert.Len(t, families, 1)
+	assert.Empty(t, families[0].Metrics[0].(prom2json.Metric).Labels)
+
+	families, err = GetMetricsFamilies(serverConfig, MetricsBoth)
+	assert.NoError(t, err)
+	assert.Len(t, families, 2)
+	//families of the same name are merged, every metric is tagged with its product
+	assert.Equal(t, "sys_load_1", families[0].Name)
+	assert.Len(t, families[0].Metrics, 2)
+	assert.Equal(t, "xray", families[0].Metrics[0].(prom2json.Metric).Labels["product"])
+	assert.Equal(t, "artifactory", families[0].Metrics[1].(prom2json.Metric).Labels["product"])
+	assert.Equal(t, "artifactory", families[1].Metrics[0].(prom2json.Metric).Labels["product"])
+
+	_, err = GetMetricsFamilies(serverConfig, "mission-control")
+	assert.Error(t, err)
+}
+
+func TestMetricsDataHistogram(t *testing.T) {
+	families, err := ParseMetrics([]byte("# HELP jfxr_db_sync_duration_seconds DB sync duration\n# TYPE jfxr_db_sync_duration_seconds histogram\n" +
+		"jfxr_db_sync_duration_seconds_bucket{le=\"1\",stage=\"persist\"} 2\njfxr_db_sync_duration_seconds_bucket{le=\"+Inf\",stage=\"persist\"} 4\n" +
+		"jfxr_db_sync_duration_seconds_sum{stage=\"persist\"} 10\njfxr_db_sync_duration_seconds_count{stage=\"persist\"} 4\n"))
+	assert.NoError(t, err)
+	jsonText, err := MetricsFamiliesJSON(families, false)
+	assert.NoError(t, err)
+	var data []Data
+	assert.NoError(t, json.Unmarshal(jsonText, &data))
+
+	metric := data[0].Metric[0]
+	assert.Equal(t, "persist", metric.Labels["stage"])
+	assert.Equal(t, "4", metric.Buckets["+Inf"])
+	assert.Equal(t, "4", metric.Count)
+	assert.Equal(t, "2.5", metric.Scalar())
+	assert.Equal(t, "7", Metrics{Value: "7"}.Scalar())
+}
+
+func TestGetProjectRepos(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/artifactory/api/repositories" || r.URL.Query().Get("project") != "acme&co" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`[{"key": "acme-docker-local"}, {"key": "acme-npm-remote"}]`))
+	}))
+	defer server.Close()
+	serverConfig := &config.ServerDetails{ArtifactoryUrl: server.URL + "/artifactory/"}
+
+	repos, err := GetProjectRepos("acme&co", serverConfig)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"acme-docker-local", "acme-npm-remote"}, repos)
+
+	_, err = GetProjectRepos("missing", serverConfig)
+	assert.Error(t, err)
+}
+
+func TestGetStatusBody(t *testing.T) {
+	var received map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = nil
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.Write([]byte(`{"status": "scanned"}`))
+	}))
+	defer server.Close()
+	serverConfig := &config.ServerDetails{XrayUrl: server.URL + "/xray/"}
+
+	//a project with quotes stays a single field instead of adding its own
+	status, scanned := GetStatus("app", "", "42", "", "build", `acme", "name": "other`, serverConfig)
+	assert.Equal(t, "scanned", status)
+	assert.True(t, scanned)
+	assert.Equal(t, map[string]string{"name": "app", "version": "42", "project": `acme", "name": "other`}, received)
+
+	GetStatus("generic-local", "generic", "/a.tar.gz", "abc", "artifact", "", serverConfig)
+	assert.Equal(t, map[string]string{"repository_pkg_type": "generic", "path": "generic-local/a.tar.gz", "sha256": "abc"}, received)
+}
+
+func TestPingProducts(t *testing.T) {
+	var pinged []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pinged = append(pinged, r.URL.Path)
+		if r.URL.Path == "/xray/api/v1/system/ping" || strings.HasPrefix(r.URL.Path, "/down/") {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("OK"))
+	}))
+	defer server.Close()
+	serverConfig := &config.ServerDetails{Url: server.URL + "/"}
+
+	//artifactory metrics do not need xray
+	assert.NoError(t, pingProducts(serverConfig, []string{MetricsArtifactory}))
+	assert.Equal(t, []string{"/artifactory/api/system/ping"}, pinged)
+
+	//both only needs one of them, like getting the metrics of both
+	pinged = nil
+	assert.NoError(t, pingProducts(serverConfig, []string{MetricsBoth}))
+	assert.Equal(t, []string{"/xray/api/v1/system/ping", "/artifactory/api/system/ping"}, pinged)
+	assert.EqualError(t, pingProducts(serverConfig, []string{MetricsXray}), "Xray is not up:")
+	assert.Error(t, pingProducts(&config.ServerDetails{Url: server.URL + "/down/"}, []string{MetricsBoth}))
+
+	assert.Error(t, pingProducts(serverConfig, []string{"distribution"}))
+}
+
+func TestGetMetricsFamiliesBothTypeMismatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/xray/api/v1/metrics":
+			w.Write([]byte("# TYPE app_uptime_seconds gauge\napp_uptime_seconds 90\n"))
+		case "/artifactory/api/v1/metrics":
+			w.Write([]byte("# TYPE app_uptime_seconds counter\napp_uptime_seconds 100\n"))
+		}
+	}))
+	defer server.Close()
+
+	//samples of a different type are not mixed into the family
+	families, err := GetMetricsFamilies(&config.ServerDetails{Url: server.URL + "/"}, MetricsBoth)
+	assert.NoError(t, err)
+	assert.Len(t, families, 1)
+	assert.Equal(t, "GAUGE", families[0].Type)
+	assert.Len(t, families[0].Metrics, 1)
+	assert.Equal(t, "xray", families[0].Metrics[0].(prom2json.Metric).Labels["product"])
+}